@@ -4,90 +4,556 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
+	"github.com/jobodd/rgst/internal/colours"
+	"github.com/jobodd/rgst/internal/columns"
+	"github.com/jobodd/rgst/internal/completion"
 	"github.com/jobodd/rgst/internal/rgst"
+	"github.com/jobodd/rgst/internal/symbols"
+	t "github.com/jobodd/rgst/internal/tree"
+	"github.com/jobodd/rgst/internal/watch"
 	"github.com/urfave/cli/v2"
 )
 
 func main() {
 	var rgstOpts rgst.Options
+	var daemonOpts rgst.Options
+	var queryOpts rgst.Options
+	var serveOpts rgst.Options
+	var checkOpts rgst.Options
+	var diffOpts rgst.Options
+	var promptOpts rgst.Options
 
 	app := &cli.App{
-		Name:  "Recursive git status",
-		Usage: "Check the status of Git repositories in subdirectories",
-		Flags: []cli.Flag{
-			&cli.UintFlag{
-				Name:        "depth",
-				Aliases:     []string{"d"},
-				Usage:       "Set the recursion depth to check for git repos. Max: 5",
-				Value:       0,
-				Destination: &rgstOpts.RecurseDepth,
+		Name:                 "Recursive git status",
+		Usage:                "Check the status of Git repositories in subdirectories",
+		EnableBashCompletion: true,
+		// globs and regular expressions can contain commas
+		DisableSliceFlagSeparator: true,
+		BashComplete:              completer(nil, knownRepos),
+		Flags: concatFlags(
+			discoveryFlags(&rgstOpts),
+			updateFlags(&rgstOpts),
+			displayFlags(&rgstOpts),
+			[]cli.Flag{
+				&cli.BoolFlag{
+					Name:        "watch",
+					Aliases:     []string{"w"},
+					Usage:       "Keep running and redraw the tree when a repository changes",
+					Destination: &rgstOpts.Watch,
+				},
+				&cli.BoolFlag{
+					Name:        "interactive",
+					Usage:       "Browse the repositories in a full-screen view, with fetch, pull, shell and editor actions",
+					Destination: &rgstOpts.Interactive,
+				},
+				&cli.BoolFlag{
+					Name:        "summary",
+					Usage:       "Print a footer with totals across all repos and the time each phase took",
+					Destination: &rgstOpts.Summary,
+				},
+				rulesFlag(&rgstOpts),
+				&cli.PathFlag{
+					Name:        "save-snapshot",
+					Usage:       "Save every repo's stats to this file, to compare against later with `rgst diff`",
+					Destination: &rgstOpts.SnapshotPath,
+				},
+				pollIntervalFlag(&rgstOpts),
+				noCacheFlag(&rgstOpts),
+				&cli.BoolFlag{
+					Name:        "no-progress",
+					Usage:       "Don't draw live progress on stderr while fetching and collecting stats",
+					Destination: &rgstOpts.NoProgress,
+				},
 			},
-			&cli.BoolFlag{
-				Name:        "fetch",
-				Aliases:     []string{"f"},
-				Usage:       "Fetch the latest changes from remote",
-				Destination: &rgstOpts.GitOptions.ShouldFetch,
+		),
+		Action: func(c *cli.Context) error {
+			if err := checkArgs(c, &rgstOpts); err != nil {
+				return err
+			}
+			return rgst.MainProcess(rgstOpts)
+		},
+		Commands: []*cli.Command{
+			{
+				Name:         "check",
+				BashComplete: completer(nil, knownRepos),
+				Usage:        "Check the repositories against a policy, exiting non-zero if any break it",
+				ArgsUsage:    "[path]",
+				Flags: concatFlags(
+					discoveryFlags(&checkOpts),
+					[]cli.Flag{
+						&cli.BoolFlag{
+							Name:        "require-clean",
+							Usage:       "Fail repos with staged, unstaged or untracked changes",
+							Destination: &checkOpts.CheckOptions.RequireClean,
+						},
+						&cli.BoolFlag{
+							Name:        "require-pushed",
+							Usage:       "Fail repos with commits not on their upstream, or no upstream at all",
+							Destination: &checkOpts.CheckOptions.RequirePushed,
+						},
+						&cli.BoolFlag{
+							Name:        "require-no-stashes",
+							Usage:       "Fail repos with stashed changes",
+							Destination: &checkOpts.CheckOptions.RequireNoStashes,
+						},
+						&cli.StringFlag{
+							Name:        "require-branch",
+							Usage:       "Fail repos that aren't on this branch",
+							Destination: &checkOpts.CheckOptions.RequireBranch,
+						},
+						&cli.IntFlag{
+							Name:        "max-behind",
+							Usage:       "Fail repos more than this many commits behind their upstream. -1 for no limit",
+							Value:       -1,
+							Destination: &checkOpts.CheckOptions.MaxBehind,
+						},
+						rulesFlag(&checkOpts),
+						noCacheFlag(&checkOpts),
+						colourFlag(&checkOpts),
+					},
+				),
+				Action: func(c *cli.Context) error {
+					if err := checkArgs(c, &checkOpts); err != nil {
+						return err
+					}
+					return rgst.Check(checkOpts)
+				},
 			},
-			&cli.BoolFlag{
-				Name:        "fetch-all",
-				Aliases:     []string{""},
-				Usage:       "Fetch the latest changes from remote, all branches",
-				Destination: &rgstOpts.GitOptions.ShouldFetch,
+			{
+				Name: "diff",
+				BashComplete: completer(map[string][]string{
+					"format": {rgst.FormatText, rgst.FormatJSON},
+				}, nil),
+				Usage:     "Show what changed since a snapshot saved with --save-snapshot, or between two snapshots",
+				ArgsUsage: "<snapshot> [later snapshot]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "format",
+						Usage:       "Output format: text or json",
+						Value:       rgst.FormatText,
+						Destination: &diffOpts.Format,
+					},
+					noCacheFlag(&diffOpts),
+					colourFlag(&diffOpts),
+				},
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() < 1 || c.Args().Len() > 2 {
+						return errors.New("Expected a snapshot to compare against, and optionally a later snapshot")
+					}
+					if err := checkColourOptions(&diffOpts); err != nil {
+						return err
+					}
+					if diffOpts.Format != rgst.FormatText && diffOpts.Format != rgst.FormatJSON {
+						return fmt.Errorf("Unknown format %q. Expected text or json", diffOpts.Format)
+					}
+					return rgst.Diff(diffOpts, c.Args().Get(0), c.Args().Get(1))
+				},
 			},
-			&cli.BoolFlag{
-				Name:        "pull",
-				Aliases:     []string{"p"},
-				Usage:       "Pull the latest changes from remote",
-				Destination: &rgstOpts.GitOptions.ShouldPull,
+			{
+				Name:         "prompt",
+				BashComplete: completer(nil, knownRepos),
+				Usage:        "Print a short summary of the workspace for a shell prompt or status line, e.g. \"12 repos, 3 dirty, 2 behind\"",
+				ArgsUsage:    "[path]",
+				Flags: concatFlags(
+					discoveryFlags(&promptOpts),
+					[]cli.Flag{
+						&cli.StringFlag{
+							Name:        "style",
+							Usage:       "Markup for the colours: plain (none, e.g. for starship), bash, zsh or tmux",
+							Value:       rgst.PromptPlain,
+							Destination: &promptOpts.PromptStyle,
+						},
+						&cli.DurationFlag{
+							Name:        "budget",
+							Usage:       "Longest to spend checking repos before falling back to cached stats",
+							Value:       rgst.DefaultPromptBudget,
+							Destination: &promptOpts.PromptBudget,
+						},
+					},
+				),
+				Action: func(c *cli.Context) error {
+					if err := checkArgs(c, &promptOpts); err != nil {
+						return err
+					}
+					if err := rgst.CheckPromptStyle(promptOpts.PromptStyle); err != nil {
+						return err
+					}
+					return rgst.Prompt(promptOpts)
+				},
 			},
-			&cli.BoolFlag{
-				Name:        "files",
-				Aliases:     []string{},
-				Usage:       "Show the list of files changed for each git directory",
-				Destination: &rgstOpts.GitOptions.ShowFiles,
+			{
+				Name:         "daemon",
+				BashComplete: completer(nil, knownRepos),
+				Usage:        "Keep repository stats up to date in the background and answer queries on a local socket",
+				ArgsUsage:    "[path]",
+				Flags: concatFlags(
+					discoveryFlags(&daemonOpts),
+					[]cli.Flag{
+						pollIntervalFlag(&daemonOpts),
+						noCacheFlag(&daemonOpts),
+						socketFlag(&daemonOpts),
+					},
+				),
+				Action: func(c *cli.Context) error {
+					if err := checkArgs(c, &daemonOpts); err != nil {
+						return err
+					}
+					return rgst.Daemon(daemonOpts)
+				},
 			},
-			// &cli.BoolFlag{
-			// 	Name:        "merge-base",
-			// 	Aliases:     []string{"m"},
-			// 	Usage:       "Show how far ahead/behind the current branch is from its merge base",
-			// 	Destination: &rgstOpts.GitOptions.ShowMergeBase,
-			// },
-			&cli.StringFlag{
-				Name:        "regex",
-				Aliases:     []string{"e"},
-				Usage:       "Filter directories with an regular expression",
-				Value:       "",
-				Destination: &rgstOpts.FilterOptions.Regex,
+			{
+				Name:         "query",
+				BashComplete: completer(nil, knownRepos),
+				Usage:        "Print the tree for a path from a running `rgst daemon`",
+				ArgsUsage:    "[path]",
+				Flags: concatFlags(
+					displayFlags(&queryOpts),
+					[]cli.Flag{
+						socketFlag(&queryOpts),
+					},
+				),
+				Action: func(c *cli.Context) error {
+					if err := checkArgs(c, &queryOpts); err != nil {
+						return err
+					}
+					return rgst.Query(queryOpts)
+				},
 			},
-			&cli.BoolFlag{
-				Name:        "invert-match",
-				Aliases:     []string{"v"},
-				Usage:       "Invert the regular expression match",
-				Destination: &rgstOpts.FilterOptions.ShouldInvertRegExp,
+			{
+				Name:         "serve",
+				BashComplete: completer(nil, knownRepos),
+				Usage:        "Serve a dashboard of the repositories over HTTP, with a JSON API",
+				ArgsUsage:    "[path]",
+				Flags: concatFlags(
+					discoveryFlags(&serveOpts),
+					[]cli.Flag{
+						&cli.StringFlag{
+							Name:        "listen",
+							Usage:       "Address to serve the dashboard on",
+							Value:       rgst.DefaultListenAddr,
+							Destination: &serveOpts.ListenAddr,
+						},
+						pollIntervalFlag(&serveOpts),
+						noCacheFlag(&serveOpts),
+					},
+				),
+				Action: func(c *cli.Context) error {
+					if err := checkArgs(c, &serveOpts); err != nil {
+						return err
+					}
+					return rgst.Serve(serveOpts)
+				},
 			},
 		},
-		Action: func(c *cli.Context) error {
-			if err := checkArgs(c, &rgstOpts); err != nil {
-				return err
+	}
+
+	app.Commands = append(app.Commands,
+		&cli.Command{
+			Name:         "completion",
+			Usage:        "Print a completion script for bash, zsh or fish",
+			ArgsUsage:    "<shell>",
+			BashComplete: completer(nil, func() []string { return completion.Shells }),
+			Action: func(c *cli.Context) error {
+				if c.Args().Len() != 1 {
+					return fmt.Errorf("Expected a shell, one of: %v", completion.Shells)
+				}
+				script, err := completion.Script(c.Args().First())
+				if err != nil {
+					return err
+				}
+				fmt.Print(script)
+				return nil
+			},
+		},
+		&cli.Command{
+			Name:  "man",
+			Usage: "Print the man page, e.g. rgst man > /usr/local/share/man/man1/rgst.1",
+			Action: func(c *cli.Context) error {
+				// the page is named after the command, not the app's title
+				c.App.Name = "rgst"
+				page, err := c.App.ToManWithSection(1)
+				if err != nil {
+					return err
+				}
+				fmt.Print(page)
+				return nil
+			},
+		},
+	)
+
+	err := app.Run(os.Args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// completer offers the values of flags that take one of a known set, and
+// otherwise flags, subcommands and the candidates from args. values
+// overrides flagValues for the command.
+func completer(values map[string][]string, args func() []string) cli.BashCompleteFunc {
+	return func(c *cli.Context) {
+		// the word before the one being completed; the last is the
+		// completion flag itself
+		prev := ""
+		if len(os.Args) > 2 {
+			prev = os.Args[len(os.Args)-2]
+		}
+
+		if name, ok := strings.CutPrefix(prev, "--"); ok {
+			candidates, found := values[name]
+			if !found {
+				if lookup, ok := flagValues[name]; ok {
+					candidates, found = lookup(), true
+				}
 			}
-			var MAX_RECURSE_DEPTH uint = 5
-			if rgstOpts.RecurseDepth > MAX_RECURSE_DEPTH {
-				fmt.Printf(
-					"Warning: Depth of %d exceeds max recursion limit of %d.\nLimiting to %d\n\n",
-					rgstOpts.RecurseDepth,
-					MAX_RECURSE_DEPTH,
-					MAX_RECURSE_DEPTH,
-				)
+			if found {
+				for _, v := range candidates {
+					fmt.Fprintln(c.App.Writer, v)
+				}
+				return
 			}
-			rgstOpts.RecurseDepth = min(MAX_RECURSE_DEPTH, rgstOpts.RecurseDepth)
-			return rgst.MainProcess(rgstOpts)
+		}
+
+		var cmd *cli.Command
+		if c.Command != nil && c.Command.Name != "" && c.Command.Name != c.App.Name {
+			cmd = c.Command
+		}
+		cli.DefaultCompleteWithFlags(cmd)(c)
+		if args != nil && !strings.HasPrefix(prev, "-") {
+			for _, arg := range args() {
+				fmt.Fprintln(c.App.Writer, arg)
+			}
+		}
+	}
+}
+
+var flagValues = map[string]func() []string{
+	"format": func() []string { return rgst.Formats },
+	"color":  func() []string { return colours.Modes },
+	"colour": func() []string { return colours.Modes },
+	"theme": func() []string {
+		var themes []string
+		for name := range colours.Themes {
+			themes = append(themes, name)
+		}
+		slices.Sort(themes)
+		return themes
+	},
+	"symbols": func() []string {
+		names := []string{symbols.Auto}
+		for _, set := range symbols.Sets {
+			names = append(names, set.Name)
+		}
+		return names
+	},
+	"columns":        columns.Names,
+	"style":          func() []string { return rgst.PromptStyles },
+	"require-branch": func() []string { return rgst.KnownBranches(cwd()) },
+}
+
+func knownRepos() []string {
+	return rgst.KnownRepos(cwd())
+}
+
+func cwd() string {
+	dir, _ := os.Getwd()
+	return dir
+}
+
+func concatFlags(flagSets ...[]cli.Flag) []cli.Flag {
+	var flags []cli.Flag
+	for _, flagSet := range flagSets {
+		flags = append(flags, flagSet...)
+	}
+	return flags
+}
+
+func discoveryFlags(rgstOpts *rgst.Options) []cli.Flag {
+	return []cli.Flag{
+		&cli.UintFlag{
+			Name:        "depth",
+			Aliases:     []string{"d"},
+			Usage:       "Set the recursion depth to check for git repos. Max: 5",
+			Value:       0,
+			Destination: &rgstOpts.RecurseDepth,
+		},
+		&cli.StringFlag{
+			Name:        "regex",
+			Aliases:     []string{"e"},
+			Usage:       "Filter directories with an regular expression",
+			Value:       "",
+			Destination: &rgstOpts.FilterOptions.Regex,
+		},
+		&cli.BoolFlag{
+			Name:        "invert-match",
+			Aliases:     []string{"v"},
+			Usage:       "Invert the regular expression match",
+			Destination: &rgstOpts.FilterOptions.ShouldInvertRegExp,
+		},
+		&cli.BoolFlag{
+			Name:        "quiet-errors",
+			Usage:       "Hide directories that couldn't be read, e.g. other users' folders, instead of showing the error",
+			Destination: &rgstOpts.FilterOptions.QuietErrors,
+		},
+		&cli.StringSliceFlag{
+			Name:  "include",
+			Usage: "Only show repos whose path from the root matches this glob, e.g. 'work/**', or with a re: prefix regular expression. Repeatable",
+		},
+		&cli.StringSliceFlag{
+			Name:  "exclude",
+			Usage: "Hide repos whose path from the root matches this glob or re: regular expression. Repeatable",
+		},
+		&cli.BoolFlag{
+			Name:        "match-absolute",
+			Usage:       "Match --include and --exclude against absolute paths",
+			Destination: &rgstOpts.FilterOptions.MatchAbsolute,
+		},
+		&cli.BoolFlag{
+			Name:        "gitignore",
+			Usage:       "Skip directories a parent repo's .gitignore ignores, e.g. dependencies checked out under build folders",
+			Destination: &rgstOpts.DiscoveryOptions.RespectGitignore,
+		},
+		&cli.BoolFlag{
+			Name:        "follow-symlinks",
+			Aliases:     []string{"L"},
+			Usage:       "Walk into symlinked directories, showing each repo once however many links lead to it",
+			Destination: &rgstOpts.DiscoveryOptions.FollowSymlinks,
 		},
 	}
+}
 
-	err := app.Run(os.Args)
-	if err != nil {
-		fmt.Println(err)
+func updateFlags(rgstOpts *rgst.Options) []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:        "fetch",
+			Aliases:     []string{"f"},
+			Usage:       "Fetch the latest changes from remote",
+			Destination: &rgstOpts.GitOptions.ShouldFetch,
+		},
+		&cli.BoolFlag{
+			Name:        "fetch-all",
+			Usage:       "Fetch the latest changes from all remotes",
+			Destination: &rgstOpts.GitOptions.ShouldFetchAll,
+		},
+		&cli.BoolFlag{
+			Name:        "pull",
+			Aliases:     []string{"p"},
+			Usage:       "Pull the latest changes from remote",
+			Destination: &rgstOpts.GitOptions.ShouldPull,
+		},
+		&cli.BoolFlag{
+			Name:        "rebase",
+			Usage:       "Pull with --rebase instead of fast-forward only",
+			Destination: &rgstOpts.GitOptions.PullRebase,
+		},
+		&cli.BoolFlag{
+			Name:        "autostash",
+			Usage:       "Stash local changes around the pull instead of skipping dirty repos",
+			Destination: &rgstOpts.GitOptions.PullAutostash,
+		},
+	}
+}
+
+func displayFlags(rgstOpts *rgst.Options) []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:        "files",
+			Aliases:     []string{},
+			Usage:       "Show the list of files changed for each git directory",
+			Destination: &rgstOpts.GitOptions.ShowFiles,
+		},
+		// &cli.BoolFlag{
+		// 	Name:        "merge-base",
+		// 	Aliases:     []string{"m"},
+		// 	Usage:       "Show how far ahead/behind the current branch is from its merge base",
+		// 	Destination: &rgstOpts.GitOptions.ShowMergeBase,
+		// },
+		&cli.StringFlag{
+			Name:        "format",
+			Usage:       "Output format: text, json, prometheus, csv, tsv, markdown or html",
+			Value:       rgst.FormatText,
+			Destination: &rgstOpts.Format,
+		},
+		&cli.BoolFlag{
+			Name:        "flat",
+			Usage:       "Print one line per repo with its path, instead of the tree",
+			Destination: &rgstOpts.Flat,
+		},
+		&cli.StringFlag{
+			Name:  "columns",
+			Usage: "Comma separated columns to show, in order, from: " + strings.Join(columns.Names(), ","),
+		},
+		&cli.StringFlag{
+			Name:  "template",
+			Usage: "Print each repo with a Go text/template instead of the tree, e.g. '{{.Path}} {{.Branch}} {{.Behind}}'",
+		},
+		colourFlag(rgstOpts),
+		themeFlag(rgstOpts),
+		&cli.StringFlag{
+			Name:        "symbols",
+			Usage:       "Symbols for the tree and columns: auto, ascii, unicode or nerd (needs a Nerd Font). auto picks unicode for UTF-8 locales",
+			Value:       symbols.Auto,
+			EnvVars:     []string{"RGST_SYMBOLS"},
+			Destination: &rgstOpts.Symbols,
+		},
+	}
+}
+
+func colourFlag(rgstOpts *rgst.Options) cli.Flag {
+	return &cli.StringFlag{
+		Name:        "color",
+		Aliases:     []string{"colour"},
+		Usage:       "When to colour the output: auto, always or never. auto honours NO_COLOR and CLICOLOR_FORCE",
+		Value:       colours.ModeAuto,
+		Destination: &rgstOpts.Colour,
+	}
+}
+
+func themeFlag(rgstOpts *rgst.Options) cli.Flag {
+	return &cli.StringFlag{
+		Name:        "theme",
+		Usage:       "Colour for neutral values: default (the terminal's foreground), dim or white",
+		Value:       "default",
+		EnvVars:     []string{"RGST_THEME"},
+		Destination: &rgstOpts.Theme,
+	}
+}
+
+func pollIntervalFlag(rgstOpts *rgst.Options) cli.Flag {
+	return &cli.DurationFlag{
+		Name:        "poll-interval",
+		Usage:       "How often to check for changes with --watch where inotify isn't available",
+		Value:       watch.DefaultPollInterval,
+		Destination: &rgstOpts.PollInterval,
+	}
+}
+
+func rulesFlag(rgstOpts *rgst.Options) cli.Flag {
+	return &cli.PathFlag{
+		Name:        "rules",
+		Usage:       "Evaluate the policy rules in this JSON file against every repo",
+		Destination: &rgstOpts.RulesFile,
+	}
+}
+
+func noCacheFlag(rgstOpts *rgst.Options) cli.Flag {
+	return &cli.BoolFlag{
+		Name:        "no-cache",
+		Usage:       "Collect fresh stats for every repo instead of reusing cached stats for unchanged repos",
+		Destination: &rgstOpts.NoCache,
+	}
+}
+
+func socketFlag(rgstOpts *rgst.Options) cli.Flag {
+	return &cli.StringFlag{
+		Name:        "socket",
+		Usage:       "Path of the daemon's Unix domain socket",
+		Value:       rgst.DefaultSocketPath(),
+		Destination: &rgstOpts.SocketPath,
 	}
 }
 
@@ -98,16 +564,45 @@ func checkArgs(c *cli.Context, rgstOpts *rgst.Options) error {
 
 	if c.Args().Len() == 1 {
 		rgstOpts.Path = c.Args().Get(0)
+		// a directory that exists but can't be read is shown with its error
+		if info, err := os.Stat(rgstOpts.Path); err != nil {
+			return err
+		} else if !info.IsDir() {
+			return fmt.Errorf("%s isn't a directory", rgstOpts.Path)
+		}
 	}
 
-	if err := checkFilterOptions(rgstOpts); err != nil {
+	checkDepth(rgstOpts)
+
+	if err := checkFilterOptions(c, rgstOpts); err != nil {
+		return err
+	}
+
+	if err := checkPullOptions(rgstOpts); err != nil {
+		return err
+	}
+
+	if err := checkDisplayOptions(c, rgstOpts); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func checkFilterOptions(rgstOpts *rgst.Options) error {
+func checkDepth(rgstOpts *rgst.Options) {
+	var MAX_RECURSE_DEPTH uint = 5
+	if rgstOpts.RecurseDepth > MAX_RECURSE_DEPTH {
+		fmt.Fprintf(os.Stderr,
+			"Warning: Depth of %d exceeds max recursion limit of %d.\nLimiting to %d\n\n",
+			rgstOpts.RecurseDepth,
+			MAX_RECURSE_DEPTH,
+			MAX_RECURSE_DEPTH,
+		)
+	}
+	rgstOpts.RecurseDepth = min(MAX_RECURSE_DEPTH, rgstOpts.RecurseDepth)
+}
+
+func checkFilterOptions(c *cli.Context, rgstOpts *rgst.Options) error {
 	if rgstOpts.FilterOptions.Regex != "" {
 		rgstOpts.FilterOptions.ShouldFilter = true
 	} else if rgstOpts.FilterOptions.ShouldInvertRegExp {
@@ -116,5 +611,125 @@ func checkFilterOptions(rgstOpts *rgst.Options) error {
 		rgstOpts.FilterOptions.ShouldFilter = false
 	}
 
+	if err := rgstOpts.FilterOptions.Compile(); err != nil {
+		return err
+	}
+
+	var err error
+	if rgstOpts.FilterOptions.Include, err = parsePatterns(c.StringSlice("include")); err != nil {
+		return fmt.Errorf("--include: %w", err)
+	}
+	if rgstOpts.FilterOptions.Exclude, err = parsePatterns(c.StringSlice("exclude")); err != nil {
+		return fmt.Errorf("--exclude: %w", err)
+	}
+
+	return nil
+}
+
+func parsePatterns(specs []string) ([]t.Pattern, error) {
+	var patterns []t.Pattern
+	for _, spec := range specs {
+		p, err := t.ParsePattern(spec)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+func checkPullOptions(rgstOpts *rgst.Options) error {
+	gitOpts := rgstOpts.GitOptions
+	if (gitOpts.PullRebase || gitOpts.PullAutostash) && !gitOpts.ShouldPull {
+		return errors.New("--rebase and --autostash only apply when pulling. (See --help for flags: --pull)")
+	}
+
 	return nil
 }
+
+func checkDisplayOptions(c *cli.Context, rgstOpts *rgst.Options) error {
+	if rgstOpts.Format == "" {
+		rgstOpts.Format = rgst.FormatText
+	}
+
+	if err := rgst.CheckFormat(rgstOpts.Format); err != nil {
+		return err
+	}
+
+	if err := checkColourOptions(rgstOpts); err != nil {
+		return err
+	}
+
+	if err := symbols.Use(rgstOpts.Symbols); err != nil {
+		return err
+	}
+
+	if c.IsSet("columns") && c.IsSet("template") {
+		return errors.New("Can't use --columns and --template together")
+	}
+
+	if c.IsSet("columns") {
+		cols, err := columns.Parse(c.String("columns"))
+		if err != nil {
+			return err
+		}
+		rgstOpts.Columns = cols
+	}
+
+	if c.IsSet("template") {
+		tmpl, err := rgst.ParseTemplate(c.String("template"))
+		if err != nil {
+			return err
+		}
+		rgstOpts.Template = tmpl
+	}
+
+	if rgstOpts.Template != nil && rgstOpts.Format != rgst.FormatText {
+		return errors.New("--template changes the text output, so it only works with --format text")
+	}
+
+	if rgstOpts.Flat && rgstOpts.Format != rgst.FormatText && rgstOpts.Format != rgst.FormatMarkdown {
+		return errors.New("--flat only works with --format text or markdown")
+	}
+
+	switch rgstOpts.Format {
+	case rgst.FormatText, rgst.FormatCSV, rgst.FormatTSV, rgst.FormatMarkdown, rgst.FormatHTML:
+	default:
+		if rgstOpts.Columns != nil {
+			return errors.New("--columns only works with --format text, csv, tsv, markdown or html")
+		}
+	}
+
+	if rgstOpts.Flat && rgstOpts.Template != nil {
+		return errors.New("--template already prints one line per repo, so can't be used with --flat")
+	}
+
+	if rgstOpts.Interactive && rgstOpts.Template != nil {
+		return errors.New("--interactive browses the tree, so can't be used with --template")
+	}
+
+	if (rgstOpts.Watch || rgstOpts.Interactive) && rgstOpts.Format != rgst.FormatText {
+		return errors.New("--watch and --interactive redraw the tree in place, so they only work with --format text")
+	}
+
+	if rgstOpts.Summary && rgstOpts.Format != rgst.FormatText {
+		return errors.New("--summary is a footer for the text tree, so it only works with --format text")
+	}
+
+	if rgstOpts.Watch && rgstOpts.Interactive {
+		return errors.New("Can't use --watch and --interactive together")
+	}
+
+	return nil
+}
+
+func checkColourOptions(rgstOpts *rgst.Options) error {
+	if err := colours.Setup(rgstOpts.Colour); err != nil {
+		return err
+	}
+
+	if rgstOpts.Theme == "" {
+		rgstOpts.Theme = "default"
+	}
+	return colours.SetTheme(rgstOpts.Theme)
+}
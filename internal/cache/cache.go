@@ -0,0 +1,342 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jobodd/rgst/internal/git"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+// bump whenever GitStats changes shape, so old entries aren't served
+// with the new fields missing
+const version = 3
+
+// Fingerprint captures everything that can change a repo's GitStats
+// without running git. If it matches, the cached stats are still valid.
+type Fingerprint struct {
+	Head     string `json:"head"`
+	Index    string `json:"index"`
+	Refs     uint64 `json:"refs"`
+	Upstream string `json:"upstream"`
+	WorkTree uint64 `json:"workTree"`
+}
+
+type Entry struct {
+	Fingerprint   Fingerprint  `json:"fingerprint"`
+	ShowMergeBase bool         `json:"showMergeBase"`
+	GitStats      git.GitStats `json:"gitStats"`
+	UpdatedAt     time.Time    `json:"updatedAt"`
+}
+
+type Cache struct {
+	path    string
+	mu      sync.Mutex
+	changed bool
+	Version int              `json:"version"`
+	Entries map[string]Entry `json:"entries"`
+	// Roots are the directories rgst has been run on, so a prompt can
+	// tell which workspace it's in
+	Roots []Root `json:"roots,omitempty"`
+}
+
+// Root is a directory rgst has been run on, with the depth and filters
+// of its last scan, so a prompt counts the same repos
+type Root struct {
+	Path    string         `json:"path"`
+	Depth   uint           `json:"depth"`
+	Filters t.SavedFilters `json:"filters"`
+}
+
+func DefaultPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "rgst", "stats.json"), nil
+}
+
+// Load reads the cache at path. A missing or unreadable cache is not an
+// error, it just starts out empty.
+func Load(path string) *Cache {
+	c := &Cache{path: path, Version: version, Entries: map[string]Entry{}}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+	var loaded Cache
+	if err := json.Unmarshal(content, &loaded); err != nil || loaded.Version != version {
+		return c
+	}
+	if loaded.Entries != nil {
+		c.Entries = loaded.Entries
+	}
+	c.Roots = loaded.Roots
+	return c
+}
+
+// Get returns the cached stats for a repo, and whether they are still
+// fresh for the given fingerprint.
+func (c *Cache) Get(repo string, fp Fingerprint, showMergeBase bool) (git.GitStats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.Entries[repo]
+	if !ok || entry.Fingerprint != fp || entry.ShowMergeBase != showMergeBase {
+		return git.GitStats{}, false
+	}
+	return entry.GitStats, true
+}
+
+// Stale returns the last stats recorded for a repo, whatever its state now
+func (c *Cache) Stale(repo string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.Entries[repo]
+	return entry, ok
+}
+
+func (c *Cache) Put(repo string, fp Fingerprint, showMergeBase bool, gitStats git.GitStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Entries[repo] = Entry{
+		Fingerprint:   fp,
+		ShowMergeBase: showMergeBase,
+		GitStats:      gitStats,
+		UpdatedAt:     time.Now(),
+	}
+	c.changed = true
+}
+
+// Prune drops repos and roots that no longer exist. It stats every one,
+// so it's left out where time is short.
+func (c *Cache) Prune() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for repo := range c.Entries {
+		if _, err := os.Stat(filepath.Join(repo, ".git")); errors.Is(err, fs.ErrNotExist) {
+			delete(c.Entries, repo)
+			c.changed = true
+		}
+	}
+	roots := len(c.Roots)
+	c.Roots = slices.DeleteFunc(c.Roots, func(root Root) bool {
+		_, err := os.Stat(root.Path)
+		return errors.Is(err, fs.ErrNotExist)
+	})
+	if len(c.Roots) != roots {
+		c.changed = true
+	}
+}
+
+// Save writes the cache if anything changed. The file is replaced
+// atomically so a concurrent run never reads half a cache.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.changed {
+		return nil
+	}
+
+	content, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".stats-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return err
+	}
+	c.changed = false
+	return nil
+}
+
+// AddRoot records a scan of root, replacing the settings of any earlier
+// scan of the same directory
+func (c *Cache) AddRoot(root Root) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := slices.IndexFunc(c.Roots, func(r Root) bool { return r.Path == root.Path })
+	switch {
+	case i == -1:
+		c.Roots = append(c.Roots, root)
+	case !reflect.DeepEqual(c.Roots[i], root):
+		c.Roots[i] = root
+	default:
+		return
+	}
+	c.changed = true
+}
+
+// Workspace returns the deepest root containing dir
+func (c *Cache) Workspace(dir string) (Root, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var found Root
+	for _, root := range c.Roots {
+		if within(dir, root.Path) && len(root.Path) > len(found.Path) {
+			found = root
+		}
+	}
+	return found, found.Path != ""
+}
+
+// Repos lists the cached repos under root, sorted
+func (c *Cache) Repos(root string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var repos []string
+	for repo := range c.Entries {
+		if within(repo, root) {
+			repos = append(repos, repo)
+		}
+	}
+	slices.Sort(repos)
+	return repos
+}
+
+func within(path string, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func FingerprintRepo(repo string) (Fingerprint, error) {
+	gitDir := git.GitDir(repo)
+
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return Fingerprint{}, err
+	}
+
+	tracked, err := git.IndexPaths(gitDir)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+
+	commonDir := git.CommonDir(gitDir)
+	fp := Fingerprint{
+		Head:     strings.TrimSpace(string(head)),
+		Refs:     refsFingerprint(commonDir),
+		Upstream: upstream(commonDir, strings.TrimSpace(string(head))),
+		WorkTree: workTreeFingerprint(repo, tracked),
+	}
+	// a repo with nothing staged yet has no index
+	if info, err := os.Stat(filepath.Join(gitDir, "index")); err == nil {
+		fp.Index = fmt.Sprintf("%d %d", info.ModTime().UnixNano(), info.Size())
+	}
+	return fp, nil
+}
+
+// workTreeFingerprint stats the files in the index, as git status does,
+// rather than walking the whole tree. New untracked files are caught by
+// the mtime of the directory they're added to, as long as it already
+// holds a tracked file or is the top of the repo.
+func workTreeFingerprint(repo string, tracked []string) uint64 {
+	h := fnv.New64a()
+	record := func(path string) {
+		if info, err := os.Lstat(filepath.Join(repo, path)); err == nil {
+			fmt.Fprintf(h, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+		} else {
+			fmt.Fprintf(h, "%s missing\n", path)
+		}
+	}
+
+	dirs := map[string]bool{".": true}
+	record(".")
+	for _, path := range tracked {
+		record(path)
+		for dir := filepath.Dir(path); !dirs[dir]; dir = filepath.Dir(dir) {
+			dirs[dir] = true
+			record(dir)
+		}
+	}
+	return h.Sum64()
+}
+
+// refs covers branch tips, remote-tracking branches, tags and the stash,
+// whether they are loose files or packed
+func refsFingerprint(gitDir string) uint64 {
+	h := fnv.New64a()
+	if info, err := os.Stat(filepath.Join(gitDir, "packed-refs")); err == nil {
+		fmt.Fprintf(h, "packed-refs %d %d\n", info.ModTime().UnixNano(), info.Size())
+	}
+	filepath.WalkDir(filepath.Join(gitDir, "refs"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err == nil {
+			fmt.Fprintf(h, "%s %s\n", path, content)
+		}
+		return nil
+	})
+	return h.Sum64()
+}
+
+// upstream reads the remote and merge ref configured for the current
+// branch, e.g. "origin refs/heads/main"
+func upstream(gitDir string, head string) string {
+	branch, ok := strings.CutPrefix(head, "ref: refs/heads/")
+	if !ok {
+		return ""
+	}
+
+	f, err := os.Open(filepath.Join(gitDir, "config"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	section := fmt.Sprintf(`[branch "%s"]`, branch)
+	inSection := false
+	var remote, merge string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inSection = line == section
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "remote":
+			remote = strings.TrimSpace(value)
+		case "merge":
+			merge = strings.TrimSpace(value)
+		}
+	}
+	if remote == "" && merge == "" {
+		return ""
+	}
+	return remote + " " + merge
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/tree"
+)
+
+func setupRepo(t *testing.T) string {
+	repo := t.TempDir()
+	cmd := exec.Command("git", "init", "--initial-branch=master")
+	cmd.Dir = repo
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %s", out)
+	}
+	return repo
+}
+
+func TestFingerprint_ChangesWithWorkTree(t *testing.T) {
+	repo := setupRepo(t)
+	before, err := FingerprintRepo(repo)
+	if err != nil {
+		t.Fatalf("Failed test with error: %s", err)
+	}
+
+	os.WriteFile(filepath.Join(repo, "foo.txt"), []byte("foo"), 0600)
+	after, _ := FingerprintRepo(repo)
+	if before == after {
+		t.Fatalf("Failed test: fingerprint didn't change after a new file")
+	}
+}
+
+func TestFingerprint_ChangesWithTrackedFile(t *testing.T) {
+	repo := setupRepo(t)
+	os.MkdirAll(filepath.Join(repo, "src"), 0700)
+	os.WriteFile(filepath.Join(repo, "src", "main.go"), []byte("package main"), 0600)
+	cmd := exec.Command("git", "add", "src/main.go")
+	cmd.Dir = repo
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git add failed: %s", out)
+	}
+	before, _ := FingerprintRepo(repo)
+
+	// an edit that hasn't been staged leaves the index alone
+	os.WriteFile(filepath.Join(repo, "src", "main.go"), []byte("package main\n\nfunc main() {}"), 0600)
+	after, _ := FingerprintRepo(repo)
+	if before.Index != after.Index || before.WorkTree == after.WorkTree {
+		t.Fatalf("Failed test: fingerprint didn't change after editing a tracked file")
+	}
+
+	os.WriteFile(filepath.Join(repo, "src", "new.go"), []byte("package main"), 0600)
+	if added, _ := FingerprintRepo(repo); added == after {
+		t.Fatalf("Failed test: fingerprint didn't change after a new file beside a tracked one")
+	}
+}
+
+func TestFingerprint_ChangesWithHead(t *testing.T) {
+	repo := setupRepo(t)
+	before, _ := FingerprintRepo(repo)
+
+	os.WriteFile(filepath.Join(repo, ".git", "HEAD"), []byte("ref: refs/heads/develop\n"), 0600)
+	after, _ := FingerprintRepo(repo)
+	if before.Head == after.Head {
+		t.Fatalf("Failed test: fingerprint didn't change after switching branch")
+	}
+}
+
+func TestUpstream(t *testing.T) {
+	repo := setupRepo(t)
+	config := "[branch \"master\"]\n\tremote = origin\n\tmerge = refs/heads/master\n"
+	f, _ := os.OpenFile(filepath.Join(repo, ".git", "config"), os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString(config)
+	f.Close()
+
+	got := upstream(filepath.Join(repo, ".git"), "ref: refs/heads/master")
+	want := "origin refs/heads/master"
+	if got != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
+
+func TestCache_SaveAndLoad(t *testing.T) {
+	repo := setupRepo(t)
+	path := filepath.Join(t.TempDir(), "rgst", "stats.json")
+	fp, _ := FingerprintRepo(repo)
+
+	c := Load(path)
+	c.Put(repo, fp, false, git.GitStats{CurrentBranch: "master"})
+	if err := c.Save(); err != nil {
+		t.Fatalf("Failed test with error: %s", err)
+	}
+
+	gitStats, ok := Load(path).Get(repo, fp, false)
+	if !ok || gitStats.CurrentBranch != "master" {
+		t.Fatalf(`Failed test: Got: %+v (fresh: %v), Want the cached stats`, gitStats, ok)
+	}
+
+	if _, ok := Load(path).Get(repo, fp, true); ok {
+		t.Fatalf("Failed test: stats collected without the merge base were served with it")
+	}
+}
+
+func TestCache_Workspace(t *testing.T) {
+	c := Load(filepath.Join(t.TempDir(), "stats.json"))
+	c.AddRoot(Root{Path: "/home/me/dev"})
+	c.AddRoot(Root{Path: "/home/me/dev/examples", Depth: 2})
+	c.Put("/home/me/dev/api", Fingerprint{}, false, git.GitStats{})
+	c.Put("/home/me/dev/examples/db", Fingerprint{}, false, git.GitStats{})
+	c.Put("/home/me/devtools", Fingerprint{}, false, git.GitStats{})
+
+	cases := map[string]string{
+		"/home/me/dev/api/cmd":         "/home/me/dev",
+		"/home/me/dev/examples":        "/home/me/dev/examples",
+		"/home/me/dev/examples/db/src": "/home/me/dev/examples",
+		"/home/me/devtools":            "",
+	}
+	for dir, want := range cases {
+		if got, _ := c.Workspace(dir); got.Path != want {
+			t.Fatalf(`Failed test: %s Got: %v, Want: %v`, dir, got, want)
+		}
+	}
+
+	repos := c.Repos("/home/me/dev")
+	if len(repos) != 2 || repos[0] != "/home/me/dev/api" || repos[1] != "/home/me/dev/examples/db" {
+		t.Fatalf(`Failed test: Got: %v, Want: [/home/me/dev/api /home/me/dev/examples/db]`, repos)
+	}
+}
+
+func TestCache_AddRoot_KeepsLatestScan(t *testing.T) {
+	c := Load(filepath.Join(t.TempDir(), "stats.json"))
+	c.AddRoot(Root{Path: "/home/me/dev"})
+	c.AddRoot(Root{Path: "/home/me/dev", Depth: 3, Filters: tree.SavedFilters{Exclude: []string{"vendor/**"}}})
+
+	root, _ := c.Workspace("/home/me/dev/api")
+	if len(c.Roots) != 1 || root.Depth != 3 || len(root.Filters.Exclude) != 1 {
+		t.Fatalf(`Failed test: Got: %+v, Want the second scan's depth and filters`, c.Roots)
+	}
+}
@@ -1,6 +1,13 @@
 package colours
 
-import "strconv"
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/jobodd/rgst/internal/term"
+)
 
 const (
 	White  = "\033[37m"
@@ -8,12 +15,102 @@ const (
 	Green  = "\033[32m"
 	Yellow = "\033[33m"
 	Blue   = "\033[34m"
+	Dim    = "\033[2m"
 	Reset  = "\033[0m"
 )
 
+const (
+	ModeAuto   = "auto"
+	ModeAlways = "always"
+	ModeNever  = "never"
+)
+
+var Modes = []string{ModeAuto, ModeAlways, ModeNever}
+
+// Themes pick the colour used for neutral values, e.g. counts of zero.
+// The terminal's own foreground reads well on both light and dark
+// backgrounds, unlike white.
+var Themes = map[string]string{
+	"default": "",
+	"dim":     Dim,
+	"white":   White,
+}
+
+// Neutral is the colour for values that don't need attention, set by
+// SetTheme
+var Neutral = ""
+
+var enabled = true
+
+// Setup decides whether to colour output for the given --color mode.
+// In auto mode NO_COLOR turns colour off and CLICOLOR_FORCE turns it on,
+// otherwise colour is used when stdout is a terminal.
+func Setup(mode string) error {
+	switch mode {
+	case ModeAlways:
+		enabled = true
+	case ModeNever:
+		enabled = false
+	case ModeAuto, "":
+		switch {
+		case os.Getenv("NO_COLOR") != "":
+			enabled = false
+		case os.Getenv("CLICOLOR_FORCE") != "" && os.Getenv("CLICOLOR_FORCE") != "0":
+			enabled = true
+		default:
+			enabled = term.IsTerminal(os.Stdout)
+		}
+	default:
+		return fmt.Errorf("Unknown colour mode %q. Expected one of: %v", mode, Modes)
+	}
+	return nil
+}
+
+func SetEnabled(on bool) {
+	enabled = on
+}
+
+func Enabled() bool {
+	return enabled
+}
+
+func SetTheme(name string) error {
+	colour, ok := Themes[name]
+	if !ok {
+		return fmt.Errorf("Unknown theme %q. Expected one of: default, dim, white", name)
+	}
+	Neutral = colour
+	return nil
+}
+
 func ColouredInt(i int, colour string) string {
 	return ColouredString(strconv.Itoa(i), colour)
 }
 func ColouredString(s string, colour string) string {
+	if !enabled || colour == "" {
+		return s
+	}
 	return colour + s + Reset
 }
+
+// VisibleWidth is the number of characters s takes up on screen, not
+// counting ANSI escape sequences
+func VisibleWidth(s string) int {
+	width := 0
+	inEscape := false
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch {
+		case inEscape:
+			if r >= '@' && r <= '~' && r != '[' {
+				inEscape = false
+			}
+		case r == '\033':
+			inEscape = true
+		default:
+			width++
+		}
+	}
+	return width
+}
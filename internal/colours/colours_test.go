@@ -0,0 +1,39 @@
+package colours
+
+import "testing"
+
+func TestSetup_Environment(t *testing.T) {
+	defer SetEnabled(true)
+
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("CLICOLOR_FORCE", "1")
+	Setup(ModeAuto)
+	if Enabled() {
+		t.Fatalf(`Failed test: Got: colour on, Want: NO_COLOR to turn it off`)
+	}
+
+	Setup(ModeAlways)
+	if !Enabled() {
+		t.Fatalf(`Failed test: Got: colour off, Want: --color always to win over NO_COLOR`)
+	}
+
+	t.Setenv("NO_COLOR", "")
+	Setup(ModeAuto)
+	if !Enabled() {
+		t.Fatalf(`Failed test: Got: colour off, Want: CLICOLOR_FORCE to turn it on`)
+	}
+
+	if got := ColouredString("x", Red); got != "\033[31mx\033[0m" {
+		t.Fatalf(`Failed test: Got: %q, Want: red x`, got)
+	}
+	SetEnabled(false)
+	if got := ColouredString("x", Red); got != "x" {
+		t.Fatalf(`Failed test: Got: %q, Want: x`, got)
+	}
+}
+
+func TestVisibleWidth(t *testing.T) {
+	if got := VisibleWidth("\033[31m↓12\033[0m"); got != 3 {
+		t.Fatalf(`Failed test: Got: %v, Want: 3`, got)
+	}
+}
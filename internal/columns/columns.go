@@ -0,0 +1,272 @@
+package columns
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jobodd/rgst/internal/colours"
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/symbols"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+// Tones say how much attention a value needs. The tree colours them and
+// reports use them as CSS classes.
+const (
+	ToneNone  = ""
+	ToneMuted = "muted"
+	ToneGood  = "good"
+	ToneWarn  = "warn"
+	ToneBad   = "bad"
+)
+
+// Column is one field of a repo's row. Value is the plain data, for
+// exports and templates; Cell is how it's drawn in the tree.
+type Column struct {
+	Name   string
+	Header string
+	Value  func(n *t.Node, now time.Time) string
+	Cell   func(n *t.Node, now time.Time) string
+	Tone   func(n *t.Node, now time.Time) string
+}
+
+var all = []Column{
+	{
+		Name:   "path",
+		Header: "Path",
+		Value:  func(n *t.Node, now time.Time) string { return n.RelPath() },
+	},
+	{
+		Name:   "branch",
+		Header: "Branch",
+		Value:  func(n *t.Node, now time.Time) string { return n.GitStats.CurrentBranch },
+	},
+	countColumn("ahead", "Ahead", func() string { return symbols.Current.Ahead }, ToneGood,
+		func(g git.GitStats) int { return g.CommitsAheadOfRemote }),
+	countColumn("behind", "Behind", func() string { return symbols.Current.Behind }, ToneBad,
+		func(g git.GitStats) int { return g.CommitsBehindRemote }),
+	mergeBaseColumn("behind-branch", "Behind branch", func() string { return symbols.Current.BehindBranch }, ToneBad,
+		func(g git.GitStats) int { return g.CommitsBehindBranch }),
+	mergeBaseColumn("ahead-branch", "Ahead of branch", func() string { return symbols.Current.AheadBranch }, ToneGood,
+		func(g git.GitStats) int { return g.CommitsAheadOfBranch }),
+	countColumn("added", "Added", func() string { return symbols.Current.Added }, ToneGood,
+		func(g git.GitStats) int { return g.FilesAddedCount }),
+	countColumn("removed", "Removed", func() string { return symbols.Current.Removed }, ToneBad,
+		func(g git.GitStats) int { return g.FilesRemovedCount }),
+	countColumn("modified", "Modified", func() string { return symbols.Current.Modified }, ToneWarn,
+		func(g git.GitStats) int { return g.FilesModifiedCount }),
+	countColumn("unstaged", "Unstaged", func() string { return symbols.Current.Unstaged }, ToneBad,
+		func(g git.GitStats) int { return g.FilesUnstagedCount }),
+	countColumn("untracked", "Untracked", func() string { return symbols.Current.Untracked }, ToneWarn,
+		func(g git.GitStats) int { return g.UntrackedCount }),
+	countColumn("dirty", "Dirty", func() string { return symbols.Current.Dirty }, ToneWarn,
+		func(g git.GitStats) int { return g.DirtyCount() }),
+	countColumn("stashes", "Stashes", func() string { return symbols.Current.Stashes }, ToneWarn,
+		func(g git.GitStats) int { return g.StashCount }),
+	{
+		Name:   "age",
+		Header: "Last commit",
+		Value: func(n *t.Node, now time.Time) string {
+			if n.GitStats.LastCommitTime == 0 {
+				return ""
+			}
+			return time.Unix(n.GitStats.LastCommitTime, 0).UTC().Format(time.RFC3339)
+		},
+		Cell: func(n *t.Node, now time.Time) string {
+			if n.GitStats.LastCommitTime == 0 {
+				return colours.ColouredString("-", colours.Neutral)
+			}
+			return colours.ColouredString(Age(time.Unix(n.GitStats.LastCommitTime, 0), now), colours.Neutral)
+		},
+		Tone: func(n *t.Node, now time.Time) string { return ToneMuted },
+	},
+	{
+		Name:   "remote",
+		Header: "Remote",
+		Value:  func(n *t.Node, now time.Time) string { return strings.Join(n.GitStats.RemoteURLs, " ") },
+		Cell: func(n *t.Node, now time.Time) string {
+			// the first is enough to recognise the repo
+			if len(n.GitStats.RemoteURLs) == 0 {
+				return "-"
+			}
+			return n.GitStats.RemoteURLs[0]
+		},
+	},
+	{
+		Name:   "tag",
+		Header: "Tag",
+		Value:  func(n *t.Node, now time.Time) string { return n.GitStats.HeadTag },
+	},
+	{
+		Name:   "update",
+		Header: "Update",
+		Value:  func(n *t.Node, now time.Time) string { return n.UpdateResult.String() },
+		Cell:   func(n *t.Node, now time.Time) string { return git.PrettyUpdateResult(n.UpdateResult) },
+		Tone: func(n *t.Node, now time.Time) string {
+			switch n.UpdateResult.Status {
+			case git.UpdateUpdated:
+				return ToneGood
+			case git.UpdateSkipped:
+				return ToneWarn
+			case git.UpdateFailed:
+				return ToneBad
+			}
+			return ToneMuted
+		},
+	},
+	{
+		Name:   "error",
+		Header: "Error",
+		Value:  func(n *t.Node, now time.Time) string { return n.Error },
+		Tone:   func(n *t.Node, now time.Time) string { return ToneBad },
+	},
+}
+
+// countColumn is a count drawn with a symbol, e.g. "↓3", in the tone's
+// colour when it's above zero. -1 means there's nothing to compare
+// against.
+func countColumn(name string, header string, symbol func() string, tone string, count func(g git.GitStats) int) Column {
+	return Column{
+		Name:   name,
+		Header: header,
+		Value: func(n *t.Node, now time.Time) string {
+			if i := count(n.GitStats); i != -1 {
+				return strconv.Itoa(i)
+			}
+			return ""
+		},
+		Cell: func(n *t.Node, now time.Time) string {
+			i := count(n.GitStats)
+			if i == -1 {
+				return colours.ColouredString("-", colours.Neutral)
+			}
+			return colours.ColouredString(symbol()+strconv.Itoa(i), ToneColour(countTone(i, tone)))
+		},
+		Tone: func(n *t.Node, now time.Time) string { return countTone(count(n.GitStats), tone) },
+	}
+}
+
+// merge base columns stay blank when there's nothing to show
+func mergeBaseColumn(name string, header string, symbol func() string, tone string, count func(g git.GitStats) int) Column {
+	col := countColumn(name, header, symbol, tone, count)
+	col.Cell = func(n *t.Node, now time.Time) string {
+		if i := count(n.GitStats); i > 0 {
+			return colours.ColouredString(symbol()+strconv.Itoa(i), ToneColour(tone))
+		}
+		return colours.ColouredString(" ", colours.Neutral)
+	}
+	return col
+}
+
+func countTone(i int, tone string) string {
+	if i > 0 {
+		return tone
+	}
+	return ToneMuted
+}
+
+func ToneColour(tone string) string {
+	switch tone {
+	case ToneGood:
+		return colours.Green
+	case ToneWarn:
+		return colours.Yellow
+	case ToneBad:
+		return colours.Red
+	case ToneMuted:
+		return colours.Neutral
+	}
+	return ""
+}
+
+// Describes says whether the column has a value for n. Directories rgst
+// couldn't read, and repos git couldn't, only have a path and an error:
+// their stats are zero values that would read as clean.
+func (c Column) Describes(n *t.Node) bool {
+	return n.IsGitRepo && n.Error == "" || c.Name == "path" || c.Name == "error"
+}
+
+// Names lists every column, for help text and errors
+func Names() []string {
+	var names []string
+	for _, c := range all {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// All is every column, for exports. The merge base columns are only
+// filled in when they were asked for.
+func All(showMergeBase bool) []Column {
+	var cols []Column
+	for _, c := range all {
+		if !showMergeBase && (c.Name == "ahead-branch" || c.Name == "behind-branch") {
+			continue
+		}
+		col, _ := find(c.Name)
+		cols = append(cols, col)
+	}
+	return cols
+}
+
+// Default is the column set used without --columns
+func Default(showMergeBase bool) []Column {
+	names := []string{"branch", "ahead", "behind", "added", "removed", "modified", "unstaged"}
+	if showMergeBase {
+		names = []string{"branch", "ahead", "behind", "behind-branch", "ahead-branch", "added", "removed", "modified", "unstaged"}
+	}
+	cols, _ := Parse(strings.Join(names, ","))
+	return cols
+}
+
+// Parse reads a comma separated list of column names, e.g.
+// "branch,ahead,behind,dirty"
+func Parse(spec string) ([]Column, error) {
+	var cols []Column
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		col, ok := find(name)
+		if !ok {
+			return nil, fmt.Errorf("Unknown column %q. Expected some of: %s", name, strings.Join(Names(), ","))
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+// find fills in a plain Cell and no Tone for columns that don't set them
+func find(name string) (Column, bool) {
+	for _, c := range all {
+		if c.Name != name {
+			continue
+		}
+		if c.Tone == nil {
+			c.Tone = func(n *t.Node, now time.Time) string { return ToneNone }
+		}
+		if c.Cell == nil {
+			value, tone := c.Value, c.Tone
+			c.Cell = func(n *t.Node, now time.Time) string {
+				return colours.ColouredString(value(n, now), ToneColour(tone(n, now)))
+			}
+		}
+		return c, true
+	}
+	return Column{}, false
+}
+
+// Age is a short, rounded duration, e.g. "5m", "3h", "12d" or "2y"
+func Age(then time.Time, now time.Time) string {
+	d := now.Sub(then)
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", max(int(d.Minutes()), 0))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	default:
+		return fmt.Sprintf("%dy", int(d.Hours()/24/365))
+	}
+}
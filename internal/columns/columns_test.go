@@ -0,0 +1,107 @@
+package columns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/tree"
+)
+
+func TestParse(t *testing.T) {
+	cols, err := Parse("branch, behind,dirty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range cols {
+		got = append(got, c.Name)
+	}
+	if len(got) != 3 || got[0] != "branch" || got[1] != "behind" || got[2] != "dirty" {
+		t.Fatalf(`Failed test: Got: %v, Want: [branch behind dirty]`, got)
+	}
+
+	if _, err := Parse("branch,colour"); err == nil {
+		t.Fatalf(`Failed test: Got: nil, Want: an error for an unknown column`)
+	}
+}
+
+func TestValues(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	root := tree.NewNode("dev", "/dev", nil)
+	n := tree.NewNode("api", "/dev/api", root)
+	n.IsGitRepo = true
+	n.GitStats = git.GitStats{
+		CurrentBranch:        "main",
+		CommitsAheadOfRemote: -1,
+		CommitsBehindRemote:  -1,
+		ChangedFiles:         []string{"[ M] go.mod", "[??] notes.txt"},
+		UntrackedCount:       1,
+		LastCommitTime:       now.Add(-3 * 24 * time.Hour).Unix(),
+		RemoteURLs:           []string{"git@github.com:org/api.git", "git@gitlab.com:org/api.git"},
+	}
+
+	cols, _ := Parse("path,branch,ahead,dirty,age,remote")
+	want := []string{"api", "main", "", "1", "2024-05-07T12:00:00Z", "git@github.com:org/api.git git@gitlab.com:org/api.git"}
+	for i, col := range cols {
+		if got := col.Value(n, now); got != want[i] {
+			t.Fatalf(`Failed test: %s Got: %v, Want: %v`, col.Name, got, want[i])
+		}
+	}
+
+	if got := cols[4].Cell(n, now); got != "3d" {
+		t.Fatalf(`Failed test: Got: %q, Want: 3d`, got)
+	}
+}
+
+func TestAge(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	cases := map[time.Duration]string{
+		-time.Minute:             "0m",
+		42 * time.Minute:         "42m",
+		5 * time.Hour:            "5h",
+		40 * 24 * time.Hour:      "40d",
+		3 * 365 * 24 * time.Hour: "3y",
+	}
+	for ago, want := range cases {
+		if got := Age(now.Add(-ago), now); got != want {
+			t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+		}
+	}
+}
+
+func TestAll_MergeBase(t *testing.T) {
+	for _, c := range All(false) {
+		if c.Name == "ahead-branch" || c.Name == "behind-branch" {
+			t.Fatalf(`Failed test: Got: %v, Want: no merge base columns`, c.Name)
+		}
+	}
+	if got, want := len(All(true)), len(Names()); got != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
+
+func TestDescribes(t *testing.T) {
+	root := tree.NewNode("dev", "/dev", nil)
+	repo := tree.NewNode("api", "/dev/api", root)
+	repo.IsGitRepo = true
+	broken := tree.NewNode("broken", "/dev/broken", root)
+	broken.IsGitRepo = true
+	broken.Error = "fatal: detected dubious ownership"
+	locked := tree.NewNode("locked", "/dev/locked", root)
+	locked.Error = "can't read directory: permission denied"
+
+	cols, _ := Parse("path,dirty,stashes,error")
+	want := map[*tree.Node][]bool{
+		repo:   {true, true, true, true},
+		broken: {true, false, false, true},
+		locked: {true, false, false, true},
+	}
+	for n, describes := range want {
+		for i, col := range cols {
+			if got := col.Describes(n); got != describes[i] {
+				t.Fatalf(`Failed test: %s %s Got: %v, Want: %v`, n.FolderName, col.Name, got, describes[i])
+			}
+		}
+	}
+}
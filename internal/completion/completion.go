@@ -0,0 +1,22 @@
+package completion
+
+import (
+	"embed"
+	"fmt"
+)
+
+// the scripts ask rgst itself for candidates, with urfave/cli's
+// --generate-bash-completion flag, so they never go out of date
+//
+//go:embed scripts
+var scripts embed.FS
+
+var Shells = []string{"bash", "zsh", "fish"}
+
+func Script(shell string) (string, error) {
+	content, err := scripts.ReadFile("scripts/rgst." + shell)
+	if err != nil {
+		return "", fmt.Errorf("Unknown shell %q. Expected one of: %v", shell, Shells)
+	}
+	return string(content), nil
+}
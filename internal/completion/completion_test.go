@@ -0,0 +1,22 @@
+package completion
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	for _, shell := range Shells {
+		script, err := Script(shell)
+		if err != nil {
+			t.Fatalf("Failed test with error: %s", err)
+		}
+		if !strings.Contains(script, "--generate-bash-completion") {
+			t.Fatalf(`Failed test: %s Got: %s, Want: a script asking rgst for candidates`, shell, script)
+		}
+	}
+
+	if _, err := Script("powershell"); err == nil {
+		t.Fatalf(`Failed test: Got: nil, Want: an error for an unknown shell`)
+	}
+}
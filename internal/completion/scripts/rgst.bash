@@ -0,0 +1,23 @@
+# bash completion for rgst
+# source this file, or save it to /etc/bash_completion.d/rgst
+
+_rgst() {
+  local cur prev words cword
+  if declare -F _init_completion >/dev/null 2>&1; then
+    _init_completion -n "=:" || return
+  else
+    COMPREPLY=()
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    words=("${COMP_WORDS[@]}")
+    cword=$COMP_CWORD
+  fi
+
+  local request=("${words[@]:0:$cword}")
+  if [[ "$cur" == "-"* ]]; then
+    request+=("$cur")
+  fi
+  local IFS=$'\n'
+  COMPREPLY=($(compgen -W "$("${request[@]}" --generate-bash-completion 2>/dev/null)" -- "$cur"))
+}
+
+complete -o bashdefault -o default -F _rgst rgst
@@ -0,0 +1,13 @@
+# fish completion for rgst
+# save this file as ~/.config/fish/completions/rgst.fish
+
+function __rgst_complete
+    set -l request (commandline -opc)
+    set -l cur (commandline -ct)
+    if string match -q -- '-*' $cur
+        set request $request $cur
+    end
+    $request --generate-bash-completion 2>/dev/null
+end
+
+complete -c rgst -a '(__rgst_complete)'
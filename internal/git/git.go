@@ -1,10 +1,13 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	// "os"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -15,56 +18,260 @@ type GitOptions struct {
 	ShouldFetch    bool
 	ShouldFetchAll bool
 	ShouldPull     bool
+	PullRebase     bool
+	PullAutostash  bool
 	ShowFiles      bool
 	ShowMergeBase  bool
 	Command        string
 }
 
+func (o GitOptions) ShouldUpdate() bool {
+	return o.ShouldFetch || o.ShouldFetchAll || o.ShouldPull
+}
+
+type UpdateStatus string
+
+const (
+	UpdateNone     UpdateStatus = ""
+	UpdateUpdated  UpdateStatus = "updated"
+	UpdateUpToDate UpdateStatus = "up to date"
+	UpdateSkipped  UpdateStatus = "skipped"
+	UpdateFailed   UpdateStatus = "failed"
+)
+
+type UpdateResult struct {
+	Status  UpdateStatus `json:"status,omitempty"`
+	Reason  string       `json:"reason,omitempty"`
+	Commits int          `json:"commits,omitempty"`
+	Refs    int          `json:"refs,omitempty"`
+	Output  string       `json:"output,omitempty"`
+}
+
 type GitStats struct {
-	CurrentBranch        string
-	RemotesCount         int
-	CommitsAheadOfRemote int
-	CommitsBehindRemote  int
-	CommitsAheadOfBranch int
-	CommitsBehindBranch  int
-	FilesAddedCount      int
-	FilesRemovedCount    int
-	FilesModifiedCount   int
-	FilesUnstagedCount   int
-	ChangedFiles         []string
-}
-
-func UpdateDirectory(absPath string, opts GitOptions) {
-	var cmd *exec.Cmd
+	CurrentBranch        string   `json:"currentBranch"`
+	RemotesCount         int      `json:"remotesCount"`
+	CommitsAheadOfRemote int      `json:"commitsAheadOfRemote"`
+	CommitsBehindRemote  int      `json:"commitsBehindRemote"`
+	CommitsAheadOfBranch int      `json:"commitsAheadOfBranch"`
+	CommitsBehindBranch  int      `json:"commitsBehindBranch"`
+	FilesAddedCount      int      `json:"filesAddedCount"`
+	FilesRemovedCount    int      `json:"filesRemovedCount"`
+	FilesModifiedCount   int      `json:"filesModifiedCount"`
+	FilesUnstagedCount   int      `json:"filesUnstagedCount"`
+	UntrackedCount       int      `json:"untrackedCount"`
+	StashCount           int      `json:"stashCount"`
+	LastCommitTime       int64    `json:"lastCommitTime"`
+	HeadTag              string   `json:"headTag"`
+	RemoteURLs           []string `json:"remoteURLs"`
+	ChangedFiles         []string `json:"changedFiles"`
+}
+
+// DirtyCount is the number of tracked files with changes
+func (g GitStats) DirtyCount() int {
+	return len(g.ChangedFiles) - g.UntrackedCount
+}
+
+func (r UpdateResult) Failed() bool {
+	return r.Status == UpdateFailed
+}
+
+func (r UpdateResult) String() string {
+	switch r.Status {
+	case UpdateUpdated:
+		switch {
+		case r.Commits == 1:
+			return "updated 1 commit"
+		case r.Commits > 1:
+			return fmt.Sprintf("updated %d commits", r.Commits)
+		case r.Refs == 1:
+			return "updated 1 ref"
+		case r.Refs > 1:
+			return fmt.Sprintf("updated %d refs", r.Refs)
+		}
+		return string(r.Status)
+	case UpdateSkipped, UpdateFailed:
+		return fmt.Sprintf("%s: %s", r.Status, r.Reason)
+	default:
+		return string(r.Status)
+	}
+}
+
+func UpdateDirectory(absPath string, opts GitOptions) UpdateResult {
 	if opts.ShouldPull {
-		cmd = exec.Command("git", "pull")
-	} else if opts.ShouldFetchAll {
-		cmd = exec.Command("git", "fetch", "--all", "--no-recurse-submodules")
-	} else {
-		cmd = exec.Command("git", "fetch", "--no-recurse-submodules")
+		return pullDirectory(absPath, opts)
 	}
 
-	cmd.Dir = absPath
-	out, err := cmd.Output()
+	remotes, err := runGitCmd(absPath, []string{"remote"})
+	if err != nil {
+		return UpdateResult{Status: UpdateFailed, Reason: "error", Output: remotes}
+	}
+	if remotes == "" {
+		return UpdateResult{Status: UpdateSkipped, Reason: "no remote"}
+	}
+
+	fetchArgs := []string{"fetch", "--no-recurse-submodules"}
+	if opts.ShouldFetchAll {
+		fetchArgs = append(fetchArgs, "--all")
+	}
 
+	out, err := runGitNetworkCmd(absPath, fetchArgs)
 	if err != nil {
-		//TODO: add to msg
+		return UpdateResult{Status: UpdateFailed, Reason: classifyFailure(out), Output: out}
+	}
+
+	// fetch is silent unless a ref moved; updated refs show as "old..new  src -> dst"
+	refs := 0
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "->") {
+			refs++
+		}
+	}
+	if refs == 0 {
+		return UpdateResult{Status: UpdateUpToDate, Output: out}
+	}
+	return UpdateResult{Status: UpdateUpdated, Refs: refs, Output: out}
+}
+
+func classifyFailure(out string) string {
+	lower := strings.ToLower(out)
+	switch {
+	case strings.Contains(lower, "not possible to fast-forward"),
+		strings.Contains(lower, "diverg"):
+		return "diverged"
+	case strings.Contains(lower, "conflict"):
+		return "conflict"
+	case strings.Contains(lower, "[rejected]"),
+		strings.Contains(lower, "! ["):
+		return "rejected"
+	case strings.Contains(lower, "authentication failed"),
+		strings.Contains(lower, "permission denied"),
+		strings.Contains(lower, "could not read username"),
+		strings.Contains(lower, "terminal prompts disabled"),
+		strings.Contains(lower, "host key verification failed"):
+		return "auth"
+	case strings.Contains(lower, "could not resolve host"),
+		strings.Contains(lower, "connection refused"),
+		strings.Contains(lower, "connection timed out"),
+		strings.Contains(lower, "operation timed out"),
+		strings.Contains(lower, "network is unreachable"),
+		strings.Contains(lower, "unable to access"),
+		strings.Contains(lower, "could not read from remote repository"):
+		return "network"
+	}
+	return "error"
+}
+
+func pullDirectory(absPath string, opts GitOptions) UpdateResult {
+	branch, err := runGitCmd(absPath, []string{"branch", "--show-current"})
+	if err != nil {
+		return UpdateResult{Status: UpdateFailed, Reason: "error", Output: branch}
+	}
+	if branch == "" {
+		return UpdateResult{Status: UpdateSkipped, Reason: "detached"}
+	}
+
+	// a fast-forward never needs to touch local changes, but git refuses
+	// to pull over them anyway unless we stash them out of the way
+	if !opts.PullAutostash {
+		status, err := runGitCmd(absPath, []string{"status", "--porcelain", "--untracked-files=no"})
+		if err != nil {
+			return UpdateResult{Status: UpdateFailed, Reason: "error", Output: status}
+		}
+		if status != "" {
+			return UpdateResult{Status: UpdateSkipped, Reason: "dirty"}
+		}
+	}
+
+	if _, err := runGitCmd(absPath, []string{"rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}"}); err != nil {
+		return UpdateResult{Status: UpdateSkipped, Reason: "no upstream"}
+	}
+
+	before, _ := runGitCmd(absPath, []string{"rev-parse", "HEAD"})
+
+	pullArgs := []string{"pull", "--no-recurse-submodules"}
+	if opts.PullRebase {
+		pullArgs = append(pullArgs, "--rebase")
 	} else {
-		if len(out) == 0 {
-			//TODO: add to msg
+		pullArgs = append(pullArgs, "--ff-only")
+	}
+	if opts.PullAutostash {
+		pullArgs = append(pullArgs, "--autostash")
+	}
+
+	out, err := runGitNetworkCmd(absPath, pullArgs)
+	if err != nil {
+		reason := classifyFailure(out)
+		// never leave a repo half way through a rebase
+		if opts.PullRebase {
+			runGitCmd(absPath, []string{"rebase", "--abort"})
 		}
+		return UpdateResult{Status: UpdateFailed, Reason: reason, Output: out}
+	}
+
+	after, _ := runGitCmd(absPath, []string{"rev-parse", "HEAD"})
+	if before == after {
+		return UpdateResult{Status: UpdateUpToDate, Output: out}
+	}
+
+	// count what came in from upstream, so rebased local commits aren't included
+	revRange := "@{u}"
+	if before != "" {
+		revRange = fmt.Sprintf("%s..@{u}", before)
+	}
+	commits := 0
+	if countOut, err := runGitCmd(absPath, []string{"rev-list", "--count", revRange}); err == nil {
+		commits, _ = strconv.Atoi(countOut)
+	}
+	return UpdateResult{Status: UpdateUpdated, Commits: commits, Output: out}
+}
+
+// GitDir resolves the git directory of a repo, following the "gitdir:"
+// file used by worktrees and submodules.
+func GitDir(repo string) string {
+	dotGit := filepath.Join(repo, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil || info.IsDir() {
+		return dotGit
+	}
+
+	content, err := os.ReadFile(dotGit)
+	if err != nil {
+		return dotGit
+	}
+	gitDir := strings.TrimSpace(strings.TrimPrefix(string(content), "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(repo, gitDir)
 	}
+	return gitDir
 }
 
 func runGitCmd(absGitDirectory string, gitArgs []string) (cmdOut string, err error) {
+	return runGitCmdContext(context.Background(), absGitDirectory, gitArgs)
+}
+
+func runGitCmdContext(ctx context.Context, absGitDirectory string, gitArgs []string) (cmdOut string, err error) {
+	cmd := exec.CommandContext(ctx, "git", gitArgs...)
+	cmd.Dir = absGitDirectory
+	cmdOutBytes, err := cmd.CombinedOutput()
+	return strings.Trim(string(cmdOutBytes), "\n"), err
+}
+
+// runs with prompts disabled so that a repo needing credentials fails
+// rather than blocking every other concurrent update
+func runGitNetworkCmd(absGitDirectory string, gitArgs []string) (cmdOut string, err error) {
 	cmd := exec.Command("git", gitArgs...)
 	cmd.Dir = absGitDirectory
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
 	cmdOutBytes, err := cmd.CombinedOutput()
 	return strings.Trim(string(cmdOutBytes), "\n"), err
 }
 
-func getGitBranch(absDir string) string {
-	cmdOut, err := runGitCmd(absDir, []string{"branch", "--show-current"})
+func getGitBranch(ctx context.Context, absDir string) string {
+	cmdOut, err := runGitCmdContext(ctx, absDir, []string{"branch", "--show-current"})
+	// killed when the caller gave up, which isn't the repo's fault
+	if ctx.Err() != nil {
+		return ""
+	}
 	if err != nil {
 		fmt.Printf("Error getting git branch: %s", cmdOut)
 		log.Fatal("Error getting git branch")
@@ -73,7 +280,10 @@ func getGitBranch(absDir string) string {
 
 	// if we've checked out a detached HEAD
 	if branchName == "" {
-		cmdOut, err = runGitCmd(absDir, []string{"rev-parse", "--abbrev-ref", "HEAD"})
+		cmdOut, err = runGitCmdContext(ctx, absDir, []string{"rev-parse", "--abbrev-ref", "HEAD"})
+		if ctx.Err() != nil {
+			return ""
+		}
 		if err != nil {
 			fmt.Printf("Error getting git branch: %s", cmdOut)
 			log.Fatal("Error getting git branch")
@@ -83,8 +293,11 @@ func getGitBranch(absDir string) string {
 	return branchName
 }
 
-func countRemotes(absDir string) int {
-	cmdOut, err := runGitCmd(absDir, []string{"remote"})
+func countRemotes(ctx context.Context, absDir string) int {
+	cmdOut, err := runGitCmdContext(ctx, absDir, []string{"remote"})
+	if ctx.Err() != nil {
+		return 0
+	}
 	if err != nil {
 		fmt.Printf("Error counting remotes. Error was: %s", err)
 		log.Fatal("Error counting remotes")
@@ -102,8 +315,8 @@ func countRemotes(absDir string) int {
 	return len(remotesList)
 }
 
-func getAheadBehindRemote(absDir string, currentBranch string) (ahead int, behind int) {
-	cmd := exec.Command("git",
+func getAheadBehindRemote(ctx context.Context, absDir string, currentBranch string) (ahead int, behind int) {
+	cmd := exec.CommandContext(ctx, "git",
 		"rev-list",
 		"--count",
 		"--left-right",
@@ -135,16 +348,16 @@ func getAheadBehindRemote(absDir string, currentBranch string) (ahead int, behin
 	return ahead, behind
 }
 
-func getAheadBehindBranched(absDir string, currentBranch string) (ahead int, behind int) {
+func getAheadBehindBranched(ctx context.Context, absDir string, currentBranch string) (ahead int, behind int) {
 	masterBranch := "master"
-	cmd := exec.Command("git", "merge-base", masterBranch, currentBranch)
+	cmd := exec.CommandContext(ctx, "git", "merge-base", masterBranch, currentBranch)
 	cmd.Dir = absDir
 	cmdOut, err := cmd.CombinedOutput()
 	if err != nil {
 		return -1, -1
 	} else {
 		mergeBase := strings.TrimSpace(string(cmdOut))
-		cmd = exec.Command("git",
+		cmd = exec.CommandContext(ctx, "git",
 			"rev-list",
 			"--count",
 			fmt.Sprintf(
@@ -169,6 +382,12 @@ func getAheadBehindBranched(absDir string, currentBranch string) (ahead int, beh
 }
 
 func GetGitStats(absDir string, gitOpts GitOptions) (GitStats, error) {
+	return GetGitStatsContext(context.Background(), absDir, gitOpts)
+}
+
+// GetGitStatsContext kills git and returns ctx's error once ctx is done,
+// so a caller with a deadline doesn't leave git running
+func GetGitStatsContext(ctx context.Context, absDir string, gitOpts GitOptions) (GitStats, error) {
 	gitStats := GitStats{
 		CurrentBranch:        "",
 		RemotesCount:         0,
@@ -183,36 +402,152 @@ func GetGitStats(absDir string, gitOpts GitOptions) (GitStats, error) {
 		ChangedFiles:         []string{},
 	}
 
-	gitStats.CurrentBranch = getGitBranch(absDir)
-	gitStats.RemotesCount = countRemotes(absDir)
+	// status is the first thing to fail on a broken repo, so check it
+	// before the helpers that exit on errors
+	changedFiles, err := getChangedFiles(ctx, absDir)
+	if err != nil {
+		return gitStats, err
+	}
 
-	gitStats.CurrentBranch = getGitBranch(absDir)
+	gitStats.CurrentBranch = getGitBranch(ctx, absDir)
+	gitStats.RemotesCount = countRemotes(ctx, absDir)
+
+	gitStats.CurrentBranch = getGitBranch(ctx, absDir)
 
 	gitStats.CommitsBehindRemote, gitStats.CommitsAheadOfRemote =
-		getAheadBehindRemote(absDir, gitStats.CurrentBranch)
+		getAheadBehindRemote(ctx, absDir, gitStats.CurrentBranch)
 
 	if gitOpts.ShowMergeBase {
 		gitStats.CommitsAheadOfBranch, gitStats.CommitsBehindBranch =
-			getAheadBehindBranched(absDir, gitStats.CurrentBranch)
+			getAheadBehindBranched(ctx, absDir, gitStats.CurrentBranch)
 	}
 
-	gitStats.ChangedFiles = getChangedFiles(absDir)
+	gitStats.ChangedFiles = changedFiles
 
 	gitStats.FilesAddedCount,
 		gitStats.FilesRemovedCount,
 		gitStats.FilesModifiedCount,
 		gitStats.FilesUnstagedCount = parsePorcelain(gitStats.ChangedFiles)
+	gitStats.UntrackedCount = countUntracked(gitStats.ChangedFiles)
+
+	if stashes, err := listStashes(ctx, absDir); err == nil {
+		gitStats.StashCount = len(stashes)
+	}
+	gitStats.LastCommitTime = getLastCommitTime(ctx, absDir)
+	gitStats.HeadTag = getHeadTag(ctx, absDir)
+	gitStats.RemoteURLs = getRemoteURLs(ctx, absDir)
 
+	// stats from killed commands would be cached as if they were real
+	if err := ctx.Err(); err != nil {
+		return gitStats, err
+	}
 	return gitStats, nil
 }
 
-func getChangedFiles(absDir string) (changedFiles []string) {
-	cmd := exec.Command("git", "status", "--porcelain")
+func ListBranches(absDir string) ([]string, error) {
+	cmdOut, err := runGitCmd(absDir, []string{"branch", "--format=%(HEAD) %(refname:short) %(upstream:track)"})
+	if err != nil {
+		return nil, fmt.Errorf("listing branches: %s", cmdOut)
+	}
+	return splitLines(cmdOut), nil
+}
+
+func ListStashes(absDir string) ([]string, error) {
+	return listStashes(context.Background(), absDir)
+}
+
+func listStashes(ctx context.Context, absDir string) ([]string, error) {
+	cmdOut, err := runGitCmdContext(ctx, absDir, []string{"stash", "list"})
+	if err != nil {
+		return nil, fmt.Errorf("listing stashes: %s", cmdOut)
+	}
+	return splitLines(cmdOut), nil
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "\n")
+}
+
+// HeadCommit returns the full hash HEAD points at, or "" before the
+// first commit
+func HeadCommit(absDir string) string {
+	cmdOut, err := runGitCmd(absDir, []string{"rev-parse", "--verify", "--quiet", "HEAD"})
+	if err != nil {
+		return ""
+	}
+	return cmdOut
+}
+
+// CountCommits counts the commits reachable from to but not from. It
+// fails if either commit isn't in the repo, e.g. after a gc.
+func CountCommits(absDir string, from string, to string) (int, error) {
+	cmdOut, err := runGitCmd(absDir, []string{"rev-list", "--count", from + ".." + to})
+	if err != nil {
+		return 0, fmt.Errorf("counting commits: %s", cmdOut)
+	}
+	return strconv.Atoi(cmdOut)
+}
+
+// unix seconds, or 0 before the first commit
+func getLastCommitTime(ctx context.Context, absDir string) int64 {
+	cmdOut, err := runGitCmdContext(ctx, absDir, []string{"log", "-1", "--format=%ct"})
+	if err != nil {
+		return 0
+	}
+	commitTime, err := strconv.ParseInt(cmdOut, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return commitTime
+}
+
+// the tag pointing at HEAD, or "" if there isn't one
+func getHeadTag(ctx context.Context, absDir string) string {
+	cmdOut, err := runGitCmdContext(ctx, absDir, []string{"describe", "--tags", "--exact-match", "HEAD"})
+	if err != nil {
+		return ""
+	}
+	return cmdOut
+}
+
+func getRemoteURLs(ctx context.Context, absDir string) []string {
+	cmdOut, err := runGitCmdContext(ctx, absDir, []string{"config", "--get-regexp", `^remote\..*\.url$`})
+	// exits 1 when there are no remotes
+	if err != nil {
+		return []string{}
+	}
+	var urls []string
+	for _, line := range splitLines(cmdOut) {
+		if _, url, found := strings.Cut(line, " "); found {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+func countUntracked(changedFiles []string) int {
+	untracked := 0
+	for _, line := range changedFiles {
+		if strings.HasPrefix(line, "[??]") {
+			untracked++
+		}
+	}
+	return untracked
+}
+
+func getChangedFiles(ctx context.Context, absDir string) (changedFiles []string, err error) {
+	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain")
 	cmd.Dir = absDir
 	statusPorcelainOut, err := cmd.Output()
 	if err != nil {
-		log.Fatal("something went wrong getting file changelist")
-		return []string{}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, errors.New(strings.SplitN(strings.TrimSpace(string(exitErr.Stderr)), "\n", 2)[0])
+		}
+		return nil, err
 	}
 	porcelainStatus := strings.TrimRight(string(statusPorcelainOut), " \n")
 	changedFiles = strings.Split(porcelainStatus, "\n")
@@ -227,7 +562,7 @@ func getChangedFiles(absDir string) (changedFiles []string) {
 			)
 		}
 	}
-	return changedFiles
+	return changedFiles, nil
 }
 
 func parsePorcelain(porcelainLines []string) (int, int, int, int) {
@@ -265,94 +600,15 @@ func parsePorcelain(porcelainLines []string) (int, int, int, int) {
 	return added, removed, modified, unstaged
 }
 
-func PrettyGitStats(g GitStats, gitOpts GitOptions) string {
-	var sb strings.Builder
-
-	nAheadRemote := fmt.Sprintf("\u2191%d", g.CommitsAheadOfRemote)
-	if g.CommitsAheadOfRemote == -1 {
-		nAheadRemote = "-"
-	}
-	if g.CommitsAheadOfRemote > 0 {
-		nAheadRemote = colours.ColouredString(nAheadRemote, colours.Green)
-	} else {
-		nAheadRemote = colours.ColouredString(nAheadRemote, colours.White)
-	}
-	sb.WriteString(nAheadRemote)
-	sb.WriteString("\t")
-
-	nBehindRemote := fmt.Sprintf("\u2193%d", g.CommitsBehindRemote)
-	if g.CommitsBehindRemote == -1 {
-		nBehindRemote = "-"
-	}
-	if g.CommitsBehindRemote > 0 {
-		nBehindRemote = colours.ColouredString(nBehindRemote, colours.Red)
-	} else {
-		nBehindRemote = colours.ColouredString(nBehindRemote, colours.White)
-	}
-	sb.WriteString(nBehindRemote)
-	sb.WriteString("\t")
-
-	if gitOpts.ShowMergeBase {
-		nBehindBranch := fmt.Sprintf("\u2190 %d", g.CommitsBehindBranch)
-		if g.CommitsBehindBranch == -1 {
-			nBehindBranch = "-"
-		}
-		if g.CommitsBehindBranch > 0 {
-			nBehindBranch = colours.ColouredString(nBehindBranch, colours.Red)
-		} else {
-			nBehindBranch = colours.ColouredString(" ", colours.White)
-		}
-		sb.WriteString(nBehindBranch)
-		sb.WriteString("\t")
-
-		nAheadBranch := fmt.Sprintf("\u2192%d", g.CommitsAheadOfBranch)
-		if g.CommitsAheadOfBranch == -1 {
-			nAheadBranch = "-"
-		}
-		if g.CommitsAheadOfBranch > 0 {
-			nAheadBranch = colours.ColouredString(nAheadBranch, colours.Green)
-		} else {
-			nAheadBranch = colours.ColouredString(" ", colours.White)
-		}
-		sb.WriteString(nAheadBranch)
-		sb.WriteString("\t")
-	}
-
-	added := fmt.Sprintf("+%d", g.FilesAddedCount)
-	if g.FilesAddedCount > 0 {
-		added = colours.ColouredString(added, colours.Green)
-	} else {
-		added = colours.ColouredString(added, colours.White)
+func PrettyUpdateResult(r UpdateResult) string {
+	switch r.Status {
+	case UpdateUpdated:
+		return colours.ColouredString(r.String(), colours.Green)
+	case UpdateSkipped:
+		return colours.ColouredString(r.String(), colours.Yellow)
+	case UpdateFailed:
+		return colours.ColouredString(r.String(), colours.Red)
+	default:
+		return colours.ColouredString(r.String(), colours.Neutral)
 	}
-	sb.WriteString(added)
-	sb.WriteString("\t")
-
-	removed := fmt.Sprintf("-%d", g.FilesRemovedCount)
-	if g.FilesRemovedCount > 0 {
-		removed = colours.ColouredString(removed, colours.Red)
-	} else {
-		removed = colours.ColouredString(removed, colours.White)
-	}
-	sb.WriteString(removed)
-	sb.WriteString("\t")
-
-	modified := fmt.Sprintf("~%d", g.FilesModifiedCount)
-	if g.FilesModifiedCount > 0 {
-		modified = colours.ColouredString(modified, colours.Yellow)
-	} else {
-		modified = colours.ColouredString(modified, colours.White)
-	}
-	sb.WriteString(modified)
-	sb.WriteString("\t")
-
-	unstaged := fmt.Sprintf("U%d", g.FilesUnstagedCount)
-	if g.FilesUnstagedCount > 0 {
-		unstaged = colours.ColouredString(unstaged, colours.Red)
-	} else {
-		unstaged = colours.ColouredString(unstaged, colours.White)
-	}
-	sb.WriteString(unstaged)
-	sb.WriteString("\t")
-
-	return sb.String()
 }
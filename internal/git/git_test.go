@@ -1,10 +1,12 @@
 package git
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -21,6 +23,7 @@ func TestMain(m *testing.M) {
 	// setup
 	mainTmpDir = path.Join(os.TempDir(), uuid.NewString())
 	os.Mkdir(mainTmpDir, 0700)
+	setupGitIdentity()
 	setupCommonCommands()
 
 	// run tests
@@ -31,6 +34,14 @@ func TestMain(m *testing.M) {
 	os.Exit(exitCode)
 }
 
+func setupGitIdentity() {
+	// commits in the test repos shouldn't depend on the user's git config
+	os.Setenv("GIT_AUTHOR_NAME", "rgst")
+	os.Setenv("GIT_AUTHOR_EMAIL", "rgst@example.com")
+	os.Setenv("GIT_COMMITTER_NAME", "rgst")
+	os.Setenv("GIT_COMMITTER_EMAIL", "rgst@example.com")
+}
+
 func setupCommonCommands() {
 	cmdsInitMaster = append(cmdsInitMaster, []string{"git", "init", "--initial-branch=master"})
 
@@ -88,7 +99,7 @@ func TestGetBranchName_NoCommits(t *testing.T) {
 	defer os.RemoveAll(tmpDir)
 	runCmds(tmpDir, cmdsInitMaster)
 
-	got := getGitBranch(tmpDir)
+	got := getGitBranch(context.Background(), tmpDir)
 	want := "master"
 	if got != want {
 		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
@@ -101,7 +112,7 @@ func TestGetBranchName_Master(t *testing.T) {
 	runCmds(tmpDir, cmdsInitMaster)
 	runCmds(tmpDir, cmdsFirstCommit)
 
-	got := getGitBranch(tmpDir)
+	got := getGitBranch(context.Background(), tmpDir)
 	want := "master"
 	if got != want {
 		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
@@ -113,7 +124,7 @@ func TestGetBranchName_Develop(t *testing.T) {
 	runCmds(tmpDir, cmdsInitMaster)
 	runCmds(tmpDir, cmdsCreateDevelopBranch)
 
-	got := getGitBranch(tmpDir)
+	got := getGitBranch(context.Background(), tmpDir)
 	want := "develop"
 	if got != want {
 		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
@@ -124,7 +135,7 @@ func TestCountRemotes_NoRemote(t *testing.T) {
 	tmpDir := createTmpSubDir()
 	runCmds(tmpDir, cmdsInitMaster)
 
-	got := countRemotes(tmpDir)
+	got := countRemotes(context.Background(), tmpDir)
 	want := 0
 	if got != want {
 		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
@@ -136,7 +147,7 @@ func TestCountRemotes_OneRemote(t *testing.T) {
 	defer os.RemoveAll(tmpRemote)
 	defer os.RemoveAll(tmpClone)
 
-	got := countRemotes(tmpClone)
+	got := countRemotes(context.Background(), tmpClone)
 	want := 1
 	if got != want {
 		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
@@ -155,7 +166,7 @@ func TestCountRemotes_TwoRemotes(t *testing.T) {
 	cmdsAddRemotes = append(cmdsAddRemotes, []string{"git", "remote", "add", "remote1", tmpRemote2})
 	runCmds(tmpClone, cmdsAddRemotes)
 
-	got := countRemotes(tmpClone)
+	got := countRemotes(context.Background(), tmpClone)
 	want := 2
 	if got != want {
 		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
@@ -229,3 +240,270 @@ func TestCommitAndChange(t *testing.T) {
 		t.Fatalf(`Failed test: Got %v added, Want: %v`, got, want)
 	}
 }
+
+func setupRemoteWithCommitAndClone() (tmpRemote string, tmpClone string) {
+	tmpRemote = setupRemote()
+	runCmds(tmpRemote, cmdsFirstCommit)
+	tmpClone = cloneFromRemote(tmpRemote)
+	return tmpRemote, tmpClone
+}
+
+func commitFile(absDir string, fileName string) {
+	var cmds [][]string
+	cmds = append(cmds, []string{"touch", fileName})
+	cmds = append(cmds, []string{"git", "add", "--all"})
+	cmds = append(cmds, []string{"git", "commit", "-m", fileName})
+	runCmds(absDir, cmds)
+}
+
+func TestPull_FastForward(t *testing.T) {
+	tmpRemote, tmpClone := setupRemoteWithCommitAndClone()
+	defer os.RemoveAll(tmpRemote)
+	defer os.RemoveAll(tmpClone)
+	commitFile(tmpRemote, "bar.txt")
+	commitFile(tmpRemote, "baz.txt")
+
+	got := UpdateDirectory(tmpClone, GitOptions{ShouldPull: true})
+	want := UpdateResult{Status: UpdateUpdated, Commits: 2}
+	if got.Status != want.Status || got.Commits != want.Commits {
+		t.Fatalf(`Failed test: Got: %v, Want: %v. Output was: %s`, got, want, got.Output)
+	}
+}
+
+func TestPull_UpToDate(t *testing.T) {
+	tmpRemote, tmpClone := setupRemoteWithCommitAndClone()
+	defer os.RemoveAll(tmpRemote)
+	defer os.RemoveAll(tmpClone)
+
+	got := UpdateDirectory(tmpClone, GitOptions{ShouldPull: true}).Status
+	want := UpdateUpToDate
+	if got != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
+
+func TestPull_SkipsDirty(t *testing.T) {
+	tmpRemote, tmpClone := setupRemoteWithCommitAndClone()
+	defer os.RemoveAll(tmpRemote)
+	defer os.RemoveAll(tmpClone)
+	commitFile(tmpRemote, "bar.txt")
+	os.WriteFile(path.Join(tmpClone, "foo.txt"), []byte("local change"), 0600)
+
+	got := UpdateDirectory(tmpClone, GitOptions{ShouldPull: true}).String()
+	want := "skipped: dirty"
+	if got != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+
+	got = UpdateDirectory(tmpClone, GitOptions{ShouldPull: true, PullAutostash: true}).String()
+	want = "updated 1 commit"
+	if got != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
+
+func TestPull_SkipsDetached(t *testing.T) {
+	tmpRemote, tmpClone := setupRemoteWithCommitAndClone()
+	defer os.RemoveAll(tmpRemote)
+	defer os.RemoveAll(tmpClone)
+	runCmds(tmpClone, [][]string{{"git", "checkout", "--detach"}})
+
+	got := UpdateDirectory(tmpClone, GitOptions{ShouldPull: true}).String()
+	want := "skipped: detached"
+	if got != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
+
+func TestPull_Diverged(t *testing.T) {
+	tmpRemote, tmpClone := setupRemoteWithCommitAndClone()
+	defer os.RemoveAll(tmpRemote)
+	defer os.RemoveAll(tmpClone)
+	commitFile(tmpRemote, "bar.txt")
+	commitFile(tmpClone, "baz.txt")
+
+	got := UpdateDirectory(tmpClone, GitOptions{ShouldPull: true}).String()
+	want := "failed: diverged"
+	if got != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+
+	got = UpdateDirectory(tmpClone, GitOptions{ShouldPull: true, PullRebase: true}).String()
+	want = "updated 1 commit"
+	if got != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
+
+func TestFetch_Updated(t *testing.T) {
+	tmpRemote, tmpClone := setupRemoteWithCommitAndClone()
+	defer os.RemoveAll(tmpRemote)
+	defer os.RemoveAll(tmpClone)
+	commitFile(tmpRemote, "bar.txt")
+
+	got := UpdateDirectory(tmpClone, GitOptions{ShouldFetch: true}).String()
+	want := "updated 1 ref"
+	if got != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+
+	got = UpdateDirectory(tmpClone, GitOptions{ShouldFetch: true}).String()
+	want = "up to date"
+	if got != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
+
+func TestFetch_NoRemote(t *testing.T) {
+	tmpDir := createTmpSubDir()
+	defer os.RemoveAll(tmpDir)
+	runCmds(tmpDir, cmdsInitMaster)
+
+	got := UpdateDirectory(tmpDir, GitOptions{ShouldFetch: true}).String()
+	want := "skipped: no remote"
+	if got != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
+
+func TestFetch_MissingRemote(t *testing.T) {
+	tmpDir := createTmpSubDir()
+	defer os.RemoveAll(tmpDir)
+	runCmds(tmpDir, cmdsInitMaster)
+	runCmds(tmpDir, [][]string{{"git", "remote", "add", "origin", path.Join(mainTmpDir, "does-not-exist")}})
+
+	got := UpdateDirectory(tmpDir, GitOptions{ShouldFetch: true})
+	if !got.Failed() {
+		t.Fatalf(`Failed test: Got: %v, Want a failure`, got)
+	}
+}
+
+func TestClassifyFailure(t *testing.T) {
+	outputs := map[string]string{
+		"fatal: Authentication failed for 'https://example.com/repo.git/'":             "auth",
+		"git@example.com: Permission denied (publickey).":                              "auth",
+		"fatal: unable to access 'https://example.com/': Could not resolve host":       "network",
+		" ! [rejected]        main       -> origin/main  (would clobber existing tag)": "rejected",
+		"fatal: Not possible to fast-forward, aborting.":                               "diverged",
+	}
+
+	for out, want := range outputs {
+		got := classifyFailure(out)
+		if got != want {
+			t.Fatalf(`Failed test: Got: %v, Want: %v. Output was: %s`, got, want, out)
+		}
+	}
+}
+
+func TestGitDir_GitFile(t *testing.T) {
+	tmpDir := createTmpSubDir()
+	defer os.RemoveAll(tmpDir)
+	os.WriteFile(path.Join(tmpDir, ".git"), []byte("gitdir: ../main/.git/worktrees/feature\n"), 0600)
+
+	got := GitDir(tmpDir)
+	want := path.Join(tmpDir, "../main/.git/worktrees/feature")
+	if got != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
+
+func TestGetGitStats_UntrackedAndStashes(t *testing.T) {
+	tmpDir := createTmpSubDir()
+	defer os.RemoveAll(tmpDir)
+	runCmds(tmpDir, cmdsInitMaster)
+	runCmds(tmpDir, cmdsFirstCommit)
+	os.WriteFile(path.Join(tmpDir, "foo.txt"), []byte("stashed"), 0600)
+	runCmds(tmpDir, [][]string{{"git", "stash"}})
+	os.WriteFile(path.Join(tmpDir, "foo.txt"), []byte("dirty"), 0600)
+	os.WriteFile(path.Join(tmpDir, "bar.txt"), []byte("untracked"), 0600)
+
+	stats, err := GetGitStats(tmpDir, GitOptions{})
+	if err != nil {
+		t.Fatalf("Failed test with error: %s", err)
+	}
+
+	if stats.StashCount != 1 || stats.UntrackedCount != 1 || stats.DirtyCount() != 1 || stats.LastCommitTime == 0 {
+		t.Fatalf(`Failed test: Got: %+v`, stats)
+	}
+}
+
+func TestGetGitStats_TagAndRemotes(t *testing.T) {
+	tmpRemote, tmpClone := setupRemoteWithCommitAndClone()
+	defer os.RemoveAll(tmpRemote)
+	defer os.RemoveAll(tmpClone)
+	runCmds(tmpClone, [][]string{{"git", "tag", "v1.0.0"}})
+
+	stats, err := GetGitStats(tmpClone, GitOptions{})
+	if err != nil {
+		t.Fatalf("Failed test with error: %s", err)
+	}
+
+	if stats.HeadTag != "v1.0.0" || len(stats.RemoteURLs) != 1 || stats.RemoteURLs[0] != tmpRemote {
+		t.Fatalf(`Failed test: Got: %+v`, stats)
+	}
+}
+
+func TestGetGitStats_BrokenIndex(t *testing.T) {
+	tmpRemote, tmpClone := setupRemoteWithCommitAndClone()
+	defer os.RemoveAll(tmpRemote)
+	defer os.RemoveAll(tmpClone)
+	if err := os.WriteFile(path.Join(tmpClone, ".git", "index"), []byte("junk"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := GetGitStats(tmpClone, GitOptions{})
+	if err == nil || !strings.Contains(err.Error(), "index") {
+		t.Fatalf(`Failed test: Got: %v, Want: an error about the index`, err)
+	}
+}
+
+func TestIndexPaths(t *testing.T) {
+	for _, version := range []string{"2", "3", "4"} {
+		tmpDir := createTmpSubDir()
+		defer os.RemoveAll(tmpDir)
+		runCmds(tmpDir, cmdsInitMaster)
+		os.MkdirAll(path.Join(tmpDir, "src", "internal"), 0700)
+		for _, name := range []string{"a.txt", "src/internal/long_file_name.go", "src/internal/long_file_other.go", "src/z"} {
+			os.WriteFile(path.Join(tmpDir, name), []byte(name), 0600)
+		}
+		runCmds(tmpDir, [][]string{
+			{"git", "add", "a.txt", "src/internal"},
+			// intent-to-add entries need the extended flags of version 3
+			{"git", "add", "--intent-to-add", "src/z"},
+			{"git", "update-index", "--index-version", version},
+		})
+
+		got, err := IndexPaths(path.Join(tmpDir, ".git"))
+		if err != nil {
+			t.Fatalf("Failed test with error: %s", err)
+		}
+		want := []string{"a.txt", "src/internal/long_file_name.go", "src/internal/long_file_other.go", "src/z"}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf(`Failed test (index version %s): Got: %v, Want: %v`, version, got, want)
+		}
+	}
+}
+
+func TestIndexPaths_NoIndex(t *testing.T) {
+	tmpDir := createTmpSubDir()
+	defer os.RemoveAll(tmpDir)
+	runCmds(tmpDir, cmdsInitMaster)
+
+	got, err := IndexPaths(path.Join(tmpDir, ".git"))
+	if err != nil || len(got) != 0 {
+		t.Fatalf(`Failed test: Got: %v (%v), Want no paths`, got, err)
+	}
+}
+
+func TestGetGitStatsContext_Cancelled(t *testing.T) {
+	tmpDir := createTmpSubDir()
+	defer os.RemoveAll(tmpDir)
+	runCmds(tmpDir, cmdsInitMaster)
+	runCmds(tmpDir, cmdsFirstCommit)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := GetGitStatsContext(ctx, tmpDir, GitOptions{}); err != context.Canceled {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, err, context.Canceled)
+	}
+}
@@ -0,0 +1,121 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CommonDir resolves where a repo's refs and config live. Worktrees keep
+// their own HEAD and index in gitDir, but share the rest.
+func CommonDir(gitDir string) string {
+	common, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	commonDir := strings.TrimSpace(string(common))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(gitDir, commonDir)
+	}
+	return commonDir
+}
+
+// IndexPaths lists the files in a repo's index, relative to its working
+// tree. A repo with nothing staged yet has no index, and so no paths.
+func IndexPaths(gitDir string) ([]string, error) {
+	index, err := os.ReadFile(filepath.Join(gitDir, "index"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseIndex(index, hashSize(CommonDir(gitDir)))
+}
+
+// parseIndex reads the entries of an index file, in any of the formats
+// git writes: https://git-scm.com/docs/index-format
+func parseIndex(index []byte, hashSize int) ([]string, error) {
+	if len(index) < 12 || string(index[:4]) != "DIRC" {
+		return nil, errors.New("not a git index")
+	}
+	version := binary.BigEndian.Uint32(index[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("unsupported index version %d", version)
+	}
+	count := binary.BigEndian.Uint32(index[8:12])
+
+	// ctime, mtime, dev, ino, mode, uid, gid and size come before the
+	// object name, and the flags after it
+	const statSize = 40
+	const extendedFlag = 0x4000
+
+	paths := make([]string, 0, count)
+	pos := 12
+	previous := ""
+	for i := uint32(0); i < count; i++ {
+		start := pos
+		pos += statSize + hashSize
+		if pos+2 > len(index) {
+			return nil, errors.New("truncated git index")
+		}
+		flags := binary.BigEndian.Uint16(index[pos:])
+		pos += 2
+		if version >= 3 && flags&extendedFlag != 0 {
+			pos += 2
+		}
+
+		var path string
+		if version == 4 {
+			// paths are stored as how much of the previous one to drop,
+			// then what to add
+			drop, n := binary.Uvarint(index[pos:])
+			if n <= 0 || int(drop) > len(previous) {
+				return nil, errors.New("corrupt git index")
+			}
+			pos += n
+			end := bytes.IndexByte(index[pos:], 0)
+			if end < 0 {
+				return nil, errors.New("truncated git index")
+			}
+			path = previous[:len(previous)-int(drop)] + string(index[pos:pos+end])
+			pos += end + 1
+		} else {
+			end := bytes.IndexByte(index[pos:], 0)
+			if end < 0 {
+				return nil, errors.New("truncated git index")
+			}
+			path = string(index[pos : pos+end])
+			// entries are padded with 1-8 NULs to a multiple of 8 bytes
+			pos = start + (pos+end-start+8)&^7
+		}
+
+		paths = append(paths, path)
+		previous = path
+	}
+	return paths, nil
+}
+
+// hashSize is the length of an object name, which depends on whether the
+// repo was created with SHA-256 object names
+func hashSize(commonDir string) int {
+	f, err := os.Open(filepath.Join(commonDir, "config"))
+	if err != nil {
+		return 20
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "objectformat") && strings.TrimSpace(value) == "sha256" {
+			return 32
+		}
+	}
+	return 20
+}
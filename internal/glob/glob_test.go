@@ -0,0 +1,40 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"release/*", "release/v1", true},
+		{"release/*", "release/v1/api", false},
+		{"release/**", "release/v1/api", true},
+		{"**/api", "api", true},
+		{"**/api", "services/backend/api", true},
+		{"services/**/api", "services/api", true},
+		{"services/**/api", "services/a/b/api", true},
+		{"services/**/api", "services/a/b/web", false},
+		{"*.go", "main.go", true},
+		{"**", "anything/at/all", true},
+		{"libs/go-?", "libs/go-x", true},
+		{"libs/[abc]*", "libs/dx", false},
+	}
+
+	for _, c := range cases {
+		got := Match(c.pattern, c.name)
+		if got != c.want {
+			t.Fatalf(`Failed test: Match(%q, %q) Got: %v, Want: %v`, c.pattern, c.name, got, c.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate("libs/[abc"); err == nil {
+		t.Fatalf("Failed test: Want an error for an unclosed class")
+	}
+	if err := Validate("services/**/api"); err != nil {
+		t.Fatalf("Failed test with error: %s", err)
+	}
+}
@@ -0,0 +1,101 @@
+package ignore
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jobodd/rgst/internal/glob"
+)
+
+// File lists directories for rgst to skip, in gitignore syntax
+const File = ".rgstignore"
+
+type pattern struct {
+	base     string
+	glob     string
+	negate   bool
+	anchored bool
+}
+
+// Matcher holds the patterns in effect in a directory, from the files in
+// it and its parents. As with gitignore, the last matching pattern wins.
+// A nil Matcher ignores nothing.
+type Matcher struct {
+	patterns []pattern
+}
+
+// AddFile returns a matcher with the patterns in file added, relative to
+// the directory base. m itself is left alone, so it can be shared between
+// sibling directories. A missing file adds nothing.
+func (m *Matcher) AddFile(base string, file string) *Matcher {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return m
+	}
+	patterns := parse(base, string(content))
+	if len(patterns) == 0 {
+		return m
+	}
+	if m == nil {
+		return &Matcher{patterns: patterns}
+	}
+	return &Matcher{patterns: append(slices.Clip(m.patterns), patterns...)}
+}
+
+func (m *Matcher) Ignored(absPath string) bool {
+	if m == nil {
+		return false
+	}
+	ignored := false
+	for _, p := range m.patterns {
+		rel, err := filepath.Rel(p.base, absPath)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+
+		// a pattern without a slash matches a name at any depth
+		name := rel
+		if !p.anchored {
+			name = path.Base(rel)
+		}
+		if glob.Match(p.glob, name) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+func parse(base string, content string) []pattern {
+	var patterns []pattern
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p := pattern{base: base}
+		if rest, ok := strings.CutPrefix(line, "!"); ok {
+			p.negate = true
+			line = rest
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+
+		// only directories are matched, so a trailing slash changes nothing
+		line = strings.TrimSuffix(line, "/")
+		p.anchored = strings.Contains(line, "/")
+		p.glob = strings.TrimPrefix(line, "/")
+		if p.glob == "" || glob.Validate(p.glob) != nil {
+			continue
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
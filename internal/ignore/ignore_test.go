@@ -0,0 +1,45 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatcher_Ignored(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, File), []byte("# build output\nnode_modules/\n/vendor\nthird_party/**/deps\n*.tmp\n!keep.tmp\n"), 0600)
+	os.MkdirAll(filepath.Join(root, "app"), 0700)
+	os.WriteFile(filepath.Join(root, "app", File), []byte("generated\n!node_modules\n"), 0600)
+
+	m := (*Matcher)(nil).AddFile(root, filepath.Join(root, File))
+	app := m.AddFile(filepath.Join(root, "app"), filepath.Join(root, "app", File))
+
+	cases := []struct {
+		matcher *Matcher
+		path    string
+		want    bool
+	}{
+		{m, "node_modules", true},
+		{m, "web/node_modules", true},
+		{m, "vendor", true},
+		{m, "lib/vendor", false},
+		{m, "third_party/a/b/deps", true},
+		{m, "third_party/deps", true},
+		{m, "cache.tmp", true},
+		{m, "keep.tmp", false},
+		{m, "app/generated", false},
+		{app, "app/generated", true},
+		{app, "app/node_modules", false},
+		{app, "web/node_modules", true},
+	}
+	for _, c := range cases {
+		if got := c.matcher.Ignored(filepath.Join(root, c.path)); got != c.want {
+			t.Fatalf(`Failed test: %s Got: %v, Want: %v`, c.path, got, c.want)
+		}
+	}
+
+	if (*Matcher)(nil).AddFile(root, filepath.Join(root, "missing")).Ignored(filepath.Join(root, "vendor")) {
+		t.Fatalf("Failed test: a missing file ignored a directory")
+	}
+}
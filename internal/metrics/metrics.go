@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jobodd/rgst/internal/git"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+type gauge struct {
+	name  string
+	help  string
+	value func(g git.GitStats, now time.Time) (float64, bool)
+}
+
+var gauges = []gauge{
+	{
+		name: "rgst_repo_commits_ahead",
+		help: "Commits on the current branch that aren't on its upstream.",
+		value: func(g git.GitStats, now time.Time) (float64, bool) {
+			return float64(g.CommitsAheadOfRemote), g.CommitsAheadOfRemote >= 0
+		},
+	},
+	{
+		name: "rgst_repo_commits_behind",
+		help: "Commits on the upstream that aren't on the current branch.",
+		value: func(g git.GitStats, now time.Time) (float64, bool) {
+			return float64(g.CommitsBehindRemote), g.CommitsBehindRemote >= 0
+		},
+	},
+	{
+		name: "rgst_repo_files_dirty",
+		help: "Tracked files with staged or unstaged changes.",
+		value: func(g git.GitStats, now time.Time) (float64, bool) {
+			return float64(g.DirtyCount()), true
+		},
+	},
+	{
+		name: "rgst_repo_files_untracked",
+		help: "Untracked files.",
+		value: func(g git.GitStats, now time.Time) (float64, bool) {
+			return float64(g.UntrackedCount), true
+		},
+	},
+	{
+		name: "rgst_repo_stashes",
+		help: "Stash entries.",
+		value: func(g git.GitStats, now time.Time) (float64, bool) {
+			return float64(g.StashCount), true
+		},
+	},
+	{
+		name: "rgst_repo_last_commit_age_seconds",
+		help: "Seconds since the commit at HEAD was made.",
+		value: func(g git.GitStats, now time.Time) (float64, bool) {
+			if g.LastCommitTime == 0 {
+				return 0, false
+			}
+			return now.Sub(time.Unix(g.LastCommitTime, 0)).Seconds(), true
+		},
+	},
+}
+
+// WritePrometheus writes a gauge per repo in the Prometheus text format,
+// as read by the node exporter's textfile collector. Values that aren't
+// known, like ahead/behind without an upstream, are left out rather
+// than reported as zero.
+func WritePrometheus(w io.Writer, root *t.Node, now time.Time) error {
+	var repos []*t.Node
+	t.Walk(root, func(n *t.Node) {
+		if n.IsGitRepo {
+			repos = append(repos, n)
+		}
+	})
+
+	for _, g := range gauges {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name); err != nil {
+			return err
+		}
+		for _, n := range repos {
+			value, ok := g.value(n.GitStats, now)
+			if !ok {
+				continue
+			}
+			_, err := fmt.Fprintf(w, "%s{path=\"%s\",branch=\"%s\"} %g\n",
+				g.name,
+				escapeLabel(n.AbsPath),
+				escapeLabel(n.GitStats.CurrentBranch),
+				value,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
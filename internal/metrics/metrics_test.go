@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/tree"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	root := tree.NewNode("dev", "/srv/dev", nil)
+	repo := tree.NewNode("api", `/srv/dev/"api"`, root)
+	repo.IsGitRepo = true
+	repo.GitStats = git.GitStats{
+		CurrentBranch:        "main",
+		CommitsAheadOfRemote: -1,
+		CommitsBehindRemote:  2,
+		UntrackedCount:       1,
+		StashCount:           3,
+		LastCommitTime:       now.Add(-time.Hour).Unix(),
+		ChangedFiles:         []string{"[ M] go.mod", "[??] notes.txt"},
+	}
+	root.Children = append(root.Children, repo)
+
+	var out bytes.Buffer
+	if err := WritePrometheus(&out, root, now); err != nil {
+		t.Fatalf("Failed test with error: %s", err)
+	}
+	got := out.String()
+
+	for _, want := range []string{
+		"# TYPE rgst_repo_commits_behind gauge\n",
+		`rgst_repo_commits_behind{path="/srv/dev/\"api\"",branch="main"} 2`,
+		`rgst_repo_files_dirty{path="/srv/dev/\"api\"",branch="main"} 1`,
+		`rgst_repo_stashes{path="/srv/dev/\"api\"",branch="main"} 3`,
+		`rgst_repo_last_commit_age_seconds{path="/srv/dev/\"api\"",branch="main"} 3600`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf(`Failed test: Want %q in output: %s`, want, got)
+		}
+	}
+	if strings.Contains(got, "rgst_repo_commits_ahead{") {
+		t.Fatalf("Failed test: ahead without an upstream should be left out: %s", got)
+	}
+}
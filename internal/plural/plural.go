@@ -0,0 +1,26 @@
+package plural
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Count writes a count with its noun, e.g. "1 commit", "2 stashes" or
+// "3 directories". Only regular English plurals are handled.
+func Count(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %s", count, Of(noun))
+}
+
+func Of(noun string) string {
+	switch {
+	case strings.HasSuffix(noun, "s"), strings.HasSuffix(noun, "x"),
+		strings.HasSuffix(noun, "ch"), strings.HasSuffix(noun, "sh"):
+		return noun + "es"
+	case strings.HasSuffix(noun, "y") && len(noun) > 1 && !strings.ContainsRune("aeiou", rune(noun[len(noun)-2])):
+		return noun[:len(noun)-1] + "ies"
+	}
+	return noun + "s"
+}
@@ -0,0 +1,23 @@
+package plural
+
+import "testing"
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		count int
+		noun  string
+		want  string
+	}{
+		{1, "commit", "1 commit"},
+		{0, "commit", "0 commits"},
+		{2, "stash", "2 stashes"},
+		{3, "directory", "3 directories"},
+		{2, "day", "2 days"},
+		{4, "box", "4 boxes"},
+	}
+	for _, tt := range tests {
+		if got := Count(tt.count, tt.noun); got != tt.want {
+			t.Fatalf(`Failed test: Got: %v, Want: %v`, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/glob"
+)
+
+type fieldKind int
+
+const (
+	numberField fieldKind = iota
+	textField
+	boolField
+)
+
+type field struct {
+	kind  fieldKind
+	value func(g git.GitStats, now time.Time) any
+}
+
+var fields = map[string]field{
+	"branch":    {textField, func(g git.GitStats, now time.Time) any { return g.CurrentBranch }},
+	"tag":       {textField, func(g git.GitStats, now time.Time) any { return g.HeadTag }},
+	"ahead":     {numberField, func(g git.GitStats, now time.Time) any { return g.CommitsAheadOfRemote }},
+	"behind":    {numberField, func(g git.GitStats, now time.Time) any { return g.CommitsBehindRemote }},
+	"added":     {numberField, func(g git.GitStats, now time.Time) any { return g.FilesAddedCount }},
+	"removed":   {numberField, func(g git.GitStats, now time.Time) any { return g.FilesRemovedCount }},
+	"modified":  {numberField, func(g git.GitStats, now time.Time) any { return g.FilesModifiedCount }},
+	"unstaged":  {numberField, func(g git.GitStats, now time.Time) any { return g.FilesUnstagedCount }},
+	"dirty":     {numberField, func(g git.GitStats, now time.Time) any { return g.DirtyCount() }},
+	"untracked": {numberField, func(g git.GitStats, now time.Time) any { return g.UntrackedCount }},
+	"stashes":   {numberField, func(g git.GitStats, now time.Time) any { return g.StashCount }},
+	"remotes":   {numberField, func(g git.GitStats, now time.Time) any { return g.RemotesCount }},
+	"age_days": {numberField, func(g git.GitStats, now time.Time) any {
+		if g.LastCommitTime == 0 {
+			return 0
+		}
+		return int(now.Sub(time.Unix(g.LastCommitTime, 0)).Hours() / 24)
+	}},
+	"clean":        {boolField, func(g git.GitStats, now time.Time) any { return len(g.ChangedFiles) == 0 }},
+	"on_tag":       {boolField, func(g git.GitStats, now time.Time) any { return g.HeadTag != "" }},
+	"has_upstream": {boolField, func(g git.GitStats, now time.Time) any { return g.CommitsAheadOfRemote >= 0 }},
+	"detached":     {boolField, func(g git.GitStats, now time.Time) any { return g.CurrentBranch == "HEAD" }},
+}
+
+// longest first, so "<=" isn't read as "<"
+var operators = []string{"==", "!=", "<=", ">=", "=~", "<", ">"}
+
+type clause struct {
+	field  string
+	op     string
+	text   string
+	number int
+}
+
+// condition is a small expression over a repo's stats, e.g.
+// "untracked <= 20 && branch =~ release/*". && binds tighter than ||.
+type condition struct {
+	source string
+	anyOf  [][]clause
+}
+
+func parseCondition(source string) (*condition, error) {
+	c := &condition{source: source}
+	for _, alternative := range strings.Split(source, "||") {
+		var allOf []clause
+		for _, part := range strings.Split(alternative, "&&") {
+			cl, err := parseClause(strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			allOf = append(allOf, cl)
+		}
+		c.anyOf = append(c.anyOf, allOf)
+	}
+	return c, nil
+}
+
+func parseClause(s string) (clause, error) {
+	if s == "" {
+		return clause{}, fmt.Errorf("empty clause")
+	}
+
+	for _, op := range operators {
+		name, value, found := strings.Cut(s, op)
+		if !found {
+			continue
+		}
+		cl := clause{
+			field: strings.TrimSpace(name),
+			op:    op,
+			text:  strings.Trim(strings.TrimSpace(value), `"`),
+		}
+		f, ok := fields[cl.field]
+		if !ok {
+			return clause{}, fmt.Errorf("unknown field %q", cl.field)
+		}
+		switch f.kind {
+		case numberField:
+			if op == "=~" {
+				return clause{}, fmt.Errorf("%s is a number, so can't be matched with =~", cl.field)
+			}
+			number, err := strconv.Atoi(cl.text)
+			if err != nil {
+				return clause{}, fmt.Errorf("%s needs a number, got %q", cl.field, cl.text)
+			}
+			cl.number = number
+		case textField:
+			if op != "==" && op != "!=" && op != "=~" {
+				return clause{}, fmt.Errorf("%s is text, so can only be compared with ==, != or =~", cl.field)
+			}
+			if err := glob.Validate(cl.text); op == "=~" && err != nil {
+				return clause{}, fmt.Errorf("bad pattern %q: %w", cl.text, err)
+			}
+		case boolField:
+			return clause{}, fmt.Errorf("%s is true or false, so is used on its own, e.g. %q or %q", cl.field, cl.field, "!"+cl.field)
+		}
+		return cl, nil
+	}
+
+	// a bare boolean field, possibly negated
+	name, negated := strings.CutPrefix(s, "!")
+	name = strings.TrimSpace(name)
+	f, ok := fields[name]
+	if !ok {
+		return clause{}, fmt.Errorf("unknown field %q", name)
+	}
+	if f.kind != boolField {
+		return clause{}, fmt.Errorf("%s needs a comparison, e.g. %q", name, name+" == 0")
+	}
+	cl := clause{field: name, op: "==", text: "true"}
+	if negated {
+		cl.op = "!="
+	}
+	return cl, nil
+}
+
+func (c *condition) holds(g git.GitStats, now time.Time) bool {
+	for _, allOf := range c.anyOf {
+		ok := true
+		for _, cl := range allOf {
+			ok = ok && cl.holds(g, now)
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (cl clause) holds(g git.GitStats, now time.Time) bool {
+	switch value := fields[cl.field].value(g, now).(type) {
+	case int:
+		return compare(value, cl.op, cl.number)
+	case bool:
+		return (value == (cl.text == "true")) == (cl.op == "==")
+	case string:
+		switch cl.op {
+		case "==":
+			return value == cl.text
+		case "!=":
+			return value != cl.text
+		case "=~":
+			return glob.Match(cl.text, value)
+		}
+	}
+	return false
+}
+
+func compare(a int, op string, b int) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+// describe lists the values the condition looked at, e.g. "untracked=34"
+func (c *condition) describe(g git.GitStats, now time.Time) string {
+	var values []string
+	seen := map[string]bool{}
+	for _, allOf := range c.anyOf {
+		for _, cl := range allOf {
+			if seen[cl.field] {
+				continue
+			}
+			seen[cl.field] = true
+			values = append(values, fmt.Sprintf("%s=%v", cl.field, fields[cl.field].value(g, now)))
+		}
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,135 @@
+package policy
+
+import (
+	"fmt"
+
+	"github.com/jobodd/rgst/internal/plural"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// the rule broken by a repo or directory rgst couldn't read, whose stats
+// can't be trusted to pass anything else
+const RuleUnreadable = "unreadable"
+
+type Rule struct {
+	Name     string
+	Severity string
+	// Check returns why a repo breaks the rule, or "" if it doesn't
+	Check func(n *t.Node) string
+}
+
+type CheckOptions struct {
+	RequireClean     bool
+	RequirePushed    bool
+	RequireNoStashes bool
+	RequireBranch    string
+	// negative means no limit
+	MaxBehind int
+}
+
+func (o CheckOptions) Rules() []Rule {
+	var rules []Rule
+
+	if o.RequireClean {
+		rules = append(rules, Rule{
+			Name:     "require-clean",
+			Severity: SeverityError,
+			Check: func(n *t.Node) string {
+				if changed := len(n.GitStats.ChangedFiles); changed > 0 {
+					return fmt.Sprintf("has %s", plural.Count(changed, "changed file"))
+				}
+				return ""
+			},
+		})
+	}
+
+	if o.RequirePushed {
+		rules = append(rules, Rule{
+			Name:     "require-pushed",
+			Severity: SeverityError,
+			Check: func(n *t.Node) string {
+				switch ahead := n.GitStats.CommitsAheadOfRemote; {
+				case ahead < 0:
+					return fmt.Sprintf("branch %s has no upstream", n.GitStats.CurrentBranch)
+				case ahead > 0:
+					return fmt.Sprintf("has %s not pushed", plural.Count(ahead, "commit"))
+				}
+				return ""
+			},
+		})
+	}
+
+	if o.RequireNoStashes {
+		rules = append(rules, Rule{
+			Name:     "require-no-stashes",
+			Severity: SeverityError,
+			Check: func(n *t.Node) string {
+				if stashes := n.GitStats.StashCount; stashes > 0 {
+					return fmt.Sprintf("has %s", plural.Count(stashes, "stash"))
+				}
+				return ""
+			},
+		})
+	}
+
+	if o.RequireBranch != "" {
+		rules = append(rules, Rule{
+			Name:     "require-branch",
+			Severity: SeverityError,
+			Check: func(n *t.Node) string {
+				if n.GitStats.CurrentBranch != o.RequireBranch {
+					return fmt.Sprintf("is on %s, not %s", n.GitStats.CurrentBranch, o.RequireBranch)
+				}
+				return ""
+			},
+		})
+	}
+
+	if o.MaxBehind >= 0 {
+		rules = append(rules, Rule{
+			Name:     "max-behind",
+			Severity: SeverityError,
+			Check: func(n *t.Node) string {
+				if behind := n.GitStats.CommitsBehindRemote; behind > o.MaxBehind {
+					return fmt.Sprintf("is %s behind its upstream (max %d)", plural.Count(behind, "commit"), o.MaxBehind)
+				}
+				return ""
+			},
+		})
+	}
+
+	return rules
+}
+
+// Evaluate records on each repo the rules it breaks, and returns how
+// many violations of each severity were found
+func Evaluate(root *t.Node, rules []Rule) map[string]int {
+	counts := map[string]int{}
+	t.Walk(root, func(n *t.Node) {
+		if !n.IsGitRepo && n.Error == "" {
+			return
+		}
+		n.Violations = nil
+		if n.Error != "" {
+			n.Violations = []t.Violation{{Rule: RuleUnreadable, Severity: SeverityError, Message: n.Error}}
+			counts[SeverityError]++
+			return
+		}
+		for _, rule := range rules {
+			if message := rule.Check(n); message != "" {
+				n.Violations = append(n.Violations, t.Violation{
+					Rule:     rule.Name,
+					Severity: rule.Severity,
+					Message:  message,
+				})
+				counts[rule.Severity]++
+			}
+		}
+	})
+	return counts
+}
@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/tree"
+)
+
+func newRepo(root *tree.Node, name string, gitStats git.GitStats) *tree.Node {
+	repo := tree.NewNode(name, root.AbsPath+"/"+name, root)
+	repo.IsGitRepo = true
+	repo.GitStats = gitStats
+	root.Children = append(root.Children, repo)
+	return repo
+}
+
+func TestEvaluate_CheckOptions(t *testing.T) {
+	root := tree.NewNode("dev", "/dev", nil)
+	clean := newRepo(root, "clean", git.GitStats{CurrentBranch: "main", CommitsAheadOfRemote: 0, CommitsBehindRemote: 0, ChangedFiles: []string{}})
+	dirty := newRepo(root, "dirty", git.GitStats{CurrentBranch: "main", CommitsAheadOfRemote: 2, CommitsBehindRemote: 5, ChangedFiles: []string{"[ M] go.mod"}})
+	noUpstream := newRepo(root, "local", git.GitStats{CurrentBranch: "spike", CommitsAheadOfRemote: -1, CommitsBehindRemote: -1, StashCount: 1})
+
+	rules := CheckOptions{
+		RequireClean:     true,
+		RequirePushed:    true,
+		RequireNoStashes: true,
+		RequireBranch:    "main",
+		MaxBehind:        3,
+	}.Rules()
+	counts := Evaluate(root, rules)
+
+	if len(clean.Violations) != 0 {
+		t.Fatalf(`Failed test: Got: %v, Want no violations`, clean.Violations)
+	}
+
+	want := []string{"has 1 changed file", "has 2 commits not pushed", "is 5 commits behind its upstream (max 3)"}
+	if len(dirty.Violations) != len(want) {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, dirty.Violations, want)
+	}
+	for i, v := range dirty.Violations {
+		if v.Message != want[i] {
+			t.Fatalf(`Failed test: Got: %v, Want: %v`, v.Message, want[i])
+		}
+	}
+
+	want = []string{"branch spike has no upstream", "has 1 stash", "is on spike, not main"}
+	for i, v := range noUpstream.Violations {
+		if v.Message != want[i] {
+			t.Fatalf(`Failed test: Got: %v, Want: %v`, v.Message, want[i])
+		}
+	}
+
+	if counts[SeverityError] != 6 {
+		t.Fatalf(`Failed test: Got %v errors, Want: 6`, counts[SeverityError])
+	}
+}
+
+func TestRules_NoneByDefault(t *testing.T) {
+	rules := CheckOptions{MaxBehind: -1}.Rules()
+	if len(rules) != 0 {
+		t.Fatalf(`Failed test: Got %v rules, Want: 0`, len(rules))
+	}
+}
+
+func TestEvaluate_Unreadable(t *testing.T) {
+	root := tree.NewNode("dev", "/dev", nil)
+	broken := newRepo(root, "broken", git.GitStats{})
+	broken.Error = "exit status 128"
+	locked := tree.NewNode("locked", "/dev/locked", root)
+	locked.Error = "can't read directory: permission denied"
+	root.Children = append(root.Children, locked)
+
+	counts := Evaluate(root, CheckOptions{MaxBehind: 3}.Rules())
+
+	for _, n := range []*tree.Node{broken, locked} {
+		want := []tree.Violation{{Rule: RuleUnreadable, Severity: SeverityError, Message: n.Error}}
+		if len(n.Violations) != 1 || n.Violations[0] != want[0] {
+			t.Fatalf(`Failed test: Got: %v, Want: %v`, n.Violations, want)
+		}
+	}
+	if counts[SeverityError] != 2 {
+		t.Fatalf(`Failed test: Got %v errors, Want: 2`, counts[SeverityError])
+	}
+}
@@ -0,0 +1,152 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jobodd/rgst/internal/glob"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+type ruleFile struct {
+	Groups map[string][]string `json:"groups"`
+	Rules  []ruleSpec          `json:"rules"`
+}
+
+type ruleSpec struct {
+	Name      string   `json:"name"`
+	Select    selector `json:"select"`
+	Condition string   `json:"condition"`
+	Severity  string   `json:"severity"`
+}
+
+// selector picks the repos a rule applies to. Every field that is set
+// has to match; an empty selector picks every repo.
+type selector struct {
+	Path   string `json:"path"`
+	Remote string `json:"remote"`
+	Group  string `json:"group"`
+}
+
+// LoadRules reads a JSON rules file, e.g.
+//
+//	{
+//	  "groups": {"services": ["services/**"]},
+//	  "rules": [
+//	    {"name": "releases-on-tag", "select": {"path": "release/*"}, "condition": "on_tag"},
+//	    {"name": "tidy", "select": {"group": "services"}, "condition": "untracked <= 20", "severity": "warning"}
+//	  ]
+//	}
+func LoadRules(path string) ([]Rule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file ruleFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for group, patterns := range file.Groups {
+		for _, pattern := range patterns {
+			if err := glob.Validate(pattern); err != nil {
+				return nil, fmt.Errorf("%s: group %q: bad pattern %q: %w", path, group, pattern, err)
+			}
+		}
+	}
+
+	var rules []Rule
+	for i, spec := range file.Rules {
+		rule, err := spec.compile(file.Groups)
+		if err != nil {
+			name := spec.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("%s: rule %s: %w", path, name, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (spec ruleSpec) compile(groups map[string][]string) (Rule, error) {
+	if spec.Name == "" {
+		return Rule{}, fmt.Errorf("needs a name")
+	}
+
+	switch spec.Severity {
+	case "":
+		spec.Severity = SeverityError
+	case SeverityError, SeverityWarning:
+	default:
+		return Rule{}, fmt.Errorf("severity must be %q or %q, got %q", SeverityError, SeverityWarning, spec.Severity)
+	}
+
+	if spec.Condition == "" {
+		return Rule{}, fmt.Errorf("needs a condition")
+	}
+	cond, err := parseCondition(spec.Condition)
+	if err != nil {
+		return Rule{}, fmt.Errorf("condition %q: %w", spec.Condition, err)
+	}
+
+	for _, pattern := range []string{spec.Select.Path, spec.Select.Remote} {
+		if err := glob.Validate(pattern); err != nil {
+			return Rule{}, fmt.Errorf("bad pattern %q: %w", pattern, err)
+		}
+	}
+	var groupPatterns []string
+	if spec.Select.Group != "" {
+		patterns, ok := groups[spec.Select.Group]
+		if !ok {
+			return Rule{}, fmt.Errorf("unknown group %q", spec.Select.Group)
+		}
+		groupPatterns = patterns
+	}
+
+	return Rule{
+		Name:     spec.Name,
+		Severity: spec.Severity,
+		Check: func(n *t.Node) string {
+			if !spec.Select.matches(n, groupPatterns) {
+				return ""
+			}
+			now := time.Now()
+			if cond.holds(n.GitStats, now) {
+				return ""
+			}
+			return fmt.Sprintf("doesn't meet %q (%s)", cond.source, cond.describe(n.GitStats, now))
+		},
+	}, nil
+}
+
+func (s selector) matches(n *t.Node, groupPatterns []string) bool {
+	relPath := n.RelPath()
+	if s.Path != "" && !glob.Match(s.Path, relPath) {
+		return false
+	}
+
+	if s.Remote != "" {
+		found := false
+		for _, url := range n.GitStats.RemoteURLs {
+			found = found || glob.Match(s.Remote, url)
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if s.Group != "" {
+		found := false
+		for _, pattern := range groupPatterns {
+			found = found || glob.Match(pattern, relPath)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
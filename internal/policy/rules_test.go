@@ -0,0 +1,92 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/tree"
+)
+
+func writeRules(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRules_Evaluate(t *testing.T) {
+	path := writeRules(t, `{
+		"groups": {"services": ["services/**"]},
+		"rules": [
+			{"name": "releases-on-tag", "select": {"path": "release/*"}, "condition": "on_tag"},
+			{"name": "tidy", "select": {"group": "services"}, "condition": "untracked <= 20", "severity": "warning"},
+			{"name": "github-main", "select": {"remote": "git@github.com:org/*"}, "condition": "branch == main || !has_upstream"}
+		]
+	}`)
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := tree.NewNode("dev", "/dev", nil)
+	release := tree.NewNode("release", "/dev/release", root)
+	root.Children = append(root.Children, release)
+	tagged := newRepo(release, "v1", git.GitStats{CurrentBranch: "main", HeadTag: "v1.0.0", CommitsBehindRemote: 0})
+	untagged := newRepo(release, "v2", git.GitStats{CurrentBranch: "main", CommitsBehindRemote: 0})
+
+	services := tree.NewNode("services", "/dev/services", root)
+	root.Children = append(root.Children, services)
+	messy := newRepo(services, "api", git.GitStats{
+		CurrentBranch:       "feature",
+		UntrackedCount:      21,
+		CommitsBehindRemote: 0,
+		RemoteURLs:          []string{"git@github.com:org/api.git"},
+	})
+	tree.LinkParents(root)
+
+	counts := Evaluate(root, rules)
+
+	if len(tagged.Violations) != 0 {
+		t.Fatalf(`Failed test: Got: %v, Want no violations`, tagged.Violations)
+	}
+
+	want := `doesn't meet "on_tag" (on_tag=false)`
+	if len(untagged.Violations) != 1 || untagged.Violations[0].Message != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, untagged.Violations, want)
+	}
+
+	if len(messy.Violations) != 2 {
+		t.Fatalf(`Failed test: Got: %v, Want 2 violations`, messy.Violations)
+	}
+	if messy.Violations[0].Rule != "tidy" || messy.Violations[0].Severity != SeverityWarning {
+		t.Fatalf(`Failed test: Got: %v, Want: tidy warning`, messy.Violations[0])
+	}
+	if messy.Violations[1].Rule != "github-main" {
+		t.Fatalf(`Failed test: Got: %v, Want: github-main`, messy.Violations[1].Rule)
+	}
+
+	if counts[SeverityError] != 2 || counts[SeverityWarning] != 1 {
+		t.Fatalf(`Failed test: Got: %v, Want: 2 errors and 1 warning`, counts)
+	}
+}
+
+func TestLoadRules_Errors(t *testing.T) {
+	cases := map[string]string{
+		`{"rules": [{"condition": "clean"}]}`:                                           "needs a name",
+		`{"rules": [{"name": "x", "condition": "untracked < many"}]}`:                   "needs a number",
+		`{"rules": [{"name": "x", "condition": "colour == red"}]}`:                      "unknown field",
+		`{"rules": [{"name": "x", "condition": "clean", "severity": "fatal"}]}`:         "severity",
+		`{"rules": [{"name": "x", "select": {"group": "nope"}, "condition": "clean"}]}`: "unknown group",
+	}
+	for content, want := range cases {
+		_, err := LoadRules(writeRules(t, content))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf(`Failed test: Got: %v, Want an error containing %q`, err, want)
+		}
+	}
+}
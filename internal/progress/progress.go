@@ -0,0 +1,164 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	refreshInterval = 100 * time.Millisecond
+	maxListed       = 3
+)
+
+type timing struct {
+	name    string
+	elapsed time.Duration
+}
+
+// Tracker draws a live progress block on a terminal. A nil *Tracker is
+// valid and does nothing, so callers don't need to check whether progress
+// is enabled.
+type Tracker struct {
+	mu       sync.Mutex
+	out      io.Writer
+	width    int
+	label    string
+	total    int
+	done     int
+	inFlight map[string]time.Time
+	finished []timing
+	drawn    int
+	stop     chan struct{}
+	stopped  sync.WaitGroup
+}
+
+func New(out io.Writer, width int, label string, total int) *Tracker {
+	p := &Tracker{
+		out:      out,
+		width:    width,
+		label:    label,
+		total:    total,
+		inFlight: map[string]time.Time{},
+		stop:     make(chan struct{}),
+	}
+
+	p.stopped.Add(1)
+	go func() {
+		defer p.stopped.Done()
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.mu.Lock()
+				p.draw()
+				p.mu.Unlock()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+func (p *Tracker) Start(name string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inFlight[name] = time.Now()
+}
+
+func (p *Tracker) Done(name string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if started, ok := p.inFlight[name]; ok {
+		p.finished = append(p.finished, timing{name, time.Since(started)})
+		delete(p.inFlight, name)
+	}
+	p.done++
+}
+
+// Stop halts the redraws and erases the progress block, leaving the
+// cursor where the block started.
+func (p *Tracker) Stop() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	p.stopped.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+}
+
+func (p *Tracker) clear() {
+	if p.drawn > 0 {
+		fmt.Fprintf(p.out, "\r\033[%dA\033[J", p.drawn)
+	}
+	p.drawn = 0
+}
+
+func (p *Tracker) draw() {
+	var inFlight []timing
+	for name, started := range p.inFlight {
+		inFlight = append(inFlight, timing{name, time.Since(started)})
+	}
+	sort.Slice(inFlight, func(i, j int) bool {
+		return inFlight[i].name < inFlight[j].name
+	})
+
+	slowest := append(append([]timing{}, inFlight...), p.finished...)
+	sort.SliceStable(slowest, func(i, j int) bool {
+		return slowest[i].elapsed > slowest[j].elapsed
+	})
+
+	lines := []string{fmt.Sprintf("%s %d/%d", p.label, p.done, p.total)}
+	if len(inFlight) > 0 {
+		lines = append(lines, "  in flight: "+joinNames(inFlight, false))
+	}
+	if len(slowest) > 0 {
+		lines = append(lines, "  slowest:   "+joinNames(slowest, true))
+	}
+
+	p.clear()
+	for _, line := range lines {
+		fmt.Fprintln(p.out, truncate(line, p.width-1))
+	}
+	p.drawn = len(lines)
+}
+
+func joinNames(timings []timing, withElapsed bool) string {
+	var names []string
+	for i, t := range timings {
+		if i == maxListed {
+			names = append(names, fmt.Sprintf("(+%d more)", len(timings)-maxListed))
+			break
+		}
+		if withElapsed {
+			names = append(names, fmt.Sprintf("%s %.1fs", t.name, t.elapsed.Seconds()))
+		} else {
+			names = append(names, t.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// a wrapped line would throw off how far up we move to redraw
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if width <= 0 || len(runes) <= width {
+		return s
+	}
+	return string(runes[:width])
+}
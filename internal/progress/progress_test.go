@@ -0,0 +1,40 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracker_DrawsInFlightAndClears(t *testing.T) {
+	var out bytes.Buffer
+	p := New(&out, 80, "fetching", 2)
+	p.Start("dbms/postgres")
+	p.Start("dbms/sqlite")
+	p.Done("dbms/sqlite")
+	time.Sleep(3 * refreshInterval / 2)
+	p.Stop()
+
+	got := out.String()
+	for _, want := range []string{"fetching 1/2", "in flight: dbms/postgres", "slowest:", "\033[J"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf(`Failed test: Want %q in output: %q`, want, got)
+		}
+	}
+}
+
+func TestTracker_Nil(t *testing.T) {
+	var p *Tracker
+	p.Start("foo")
+	p.Done("foo")
+	p.Stop()
+}
+
+func TestTruncate(t *testing.T) {
+	got := truncate("↑↓ abcdef", 4)
+	want := "↑↓ a"
+	if got != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
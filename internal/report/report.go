@@ -0,0 +1,168 @@
+package report
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jobodd/rgst/internal/columns"
+	"github.com/jobodd/rgst/internal/plural"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+//go:embed templates/report.html
+var templates embed.FS
+
+var page = template.Must(template.ParseFS(templates, "templates/report.html"))
+
+type Options struct {
+	Columns   []columns.Column
+	Flat      bool
+	ShowFiles bool
+}
+
+type cell struct {
+	Text string
+	Tone string
+}
+
+type row struct {
+	Node  *t.Node
+	Cells []cell
+}
+
+// rows has a row per repo or unreadable directory, with its path first
+// unless the columns already include it
+func rows(root *t.Node, cols []columns.Column, now time.Time) ([]string, []row) {
+	headers := []string{"Path"}
+	for _, col := range cols {
+		if col.Name == "path" {
+			headers = nil
+		}
+	}
+	for _, col := range cols {
+		headers = append(headers, col.Header)
+	}
+
+	var result []row
+	t.Walk(root, func(n *t.Node) {
+		if !n.IsGitRepo && n.Error == "" {
+			return
+		}
+		r := row{Node: n}
+		if len(headers) > len(cols) {
+			r.Cells = append(r.Cells, cell{Text: n.RelPath()})
+		}
+		for _, col := range cols {
+			if !col.Describes(n) {
+				r.Cells = append(r.Cells, cell{})
+				continue
+			}
+			r.Cells = append(r.Cells, cell{Text: col.Value(n, now), Tone: col.Tone(n, now)})
+		}
+		result = append(result, r)
+	})
+	return headers, result
+}
+
+// WriteMarkdown writes the tree as a nested list, or with Flat as a
+// GitHub-flavoured table
+func WriteMarkdown(w io.Writer, root *t.Node, opts Options, now time.Time) error {
+	var sb strings.Builder
+	if opts.Flat {
+		headers, repos := rows(root, opts.Columns, now)
+		writeTableRow(&sb, headers)
+		sb.WriteString("|" + strings.Repeat(" --- |", len(headers)) + "\n")
+		for _, r := range repos {
+			var texts []string
+			for _, c := range r.Cells {
+				texts = append(texts, c.Text)
+			}
+			writeTableRow(&sb, texts)
+		}
+	} else {
+		t.Walk(root, func(n *t.Node) {
+			indent := strings.Repeat("  ", n.GetDepth())
+			if !n.IsGitRepo {
+				fmt.Fprintf(&sb, "%s- %s", indent, escapeMarkdown(n.FolderName))
+				if n.Error != "" {
+					fmt.Fprintf(&sb, " error: %s", escapeMarkdown(n.Error))
+				}
+				sb.WriteString("\n")
+				return
+			}
+			fmt.Fprintf(&sb, "%s- **%s**", indent, escapeMarkdown(n.FolderName))
+			if n.Error != "" {
+				fmt.Fprintf(&sb, " error: %s\n", escapeMarkdown(n.Error))
+				return
+			}
+			var fields []string
+			for _, col := range opts.Columns {
+				if value := col.Value(n, now); value != "" {
+					fields = append(fields, fmt.Sprintf("%s %s", col.Header, escapeMarkdown(value)))
+				}
+			}
+			sb.WriteString(" " + strings.Join(fields, " · ") + "\n")
+			writeDetails(&sb, n, indent+"  ", opts.ShowFiles)
+		})
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeDetails(sb *strings.Builder, n *t.Node, indent string, showFiles bool) {
+	for _, v := range n.Violations {
+		fmt.Fprintf(sb, "%s- %s: [%s] %s\n", indent, v.Severity, escapeMarkdown(v.Rule), escapeMarkdown(v.Message))
+	}
+	if showFiles {
+		for _, file := range n.GitStats.ChangedFiles {
+			fmt.Fprintf(sb, "%s- `%s`\n", indent, strings.ReplaceAll(file, "`", "'"))
+		}
+	}
+}
+
+func writeTableRow(sb *strings.Builder, cells []string) {
+	sb.WriteString("|")
+	for _, c := range cells {
+		sb.WriteString(" " + escapeMarkdown(c) + " |")
+	}
+	sb.WriteString("\n")
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// WriteHTML writes a standalone page with a row per repo and collapsible
+// lists of changed files
+func WriteHTML(w io.Writer, root *t.Node, opts Options, now time.Time) error {
+	headers, repos := rows(root, opts.Columns, now)
+	count := 0
+	for _, r := range repos {
+		if r.Node.IsGitRepo {
+			count++
+		}
+	}
+	return page.Execute(w, struct {
+		Root      string
+		Repos     string
+		Generated string
+		Headers   []string
+		Rows      []row
+	}{
+		Root:      root.AbsPath,
+		Repos:     plural.Count(count, "repo"),
+		Generated: now.Format("2006-01-02 15:04 MST"),
+		Headers:   headers,
+		Rows:      repos,
+	})
+}
@@ -0,0 +1,88 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jobodd/rgst/internal/columns"
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/tree"
+)
+
+func testTree() *tree.Node {
+	root := tree.NewNode("dev", "/dev", nil)
+	libs := tree.NewNode("libs", "/dev/libs", root)
+	root.Children = append(root.Children, libs)
+	n := tree.NewNode("a|b", "/dev/libs/a|b", libs)
+	libs.Children = append(libs.Children, n)
+	n.IsGitRepo = true
+	n.GitStats = git.GitStats{
+		CurrentBranch:       "main",
+		CommitsBehindRemote: 2,
+		ChangedFiles:        []string{"[ M] <go.mod>"},
+		FilesModifiedCount:  1,
+	}
+	return root
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	cols, _ := columns.Parse("branch,behind")
+
+	var sb strings.Builder
+	if err := WriteMarkdown(&sb, testTree(), Options{Columns: cols, ShowFiles: true}, now); err != nil {
+		t.Fatal(err)
+	}
+	want := "- dev\n  - libs\n    - **a\\|b** Branch main · Behind 2\n      - `[ M] <go.mod>`\n"
+	if sb.String() != want {
+		t.Fatalf(`Failed test: Got: %q, Want: %q`, sb.String(), want)
+	}
+
+	sb.Reset()
+	if err := WriteMarkdown(&sb, testTree(), Options{Columns: cols, Flat: true}, now); err != nil {
+		t.Fatal(err)
+	}
+	want = "| Path | Branch | Behind |\n| --- | --- | --- |\n| libs/a\\|b | main | 2 |\n"
+	if sb.String() != want {
+		t.Fatalf(`Failed test: Got: %q, Want: %q`, sb.String(), want)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	cols, _ := columns.Parse("branch,behind")
+
+	var sb strings.Builder
+	if err := WriteHTML(&sb, testTree(), Options{Columns: cols}, now); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<td class="bad">2</td>`,
+		"<summary>1 changed file</summary>",
+		"<code>[ M] &lt;go.mod&gt;</code>",
+		"1 repo, generated 2024-05-10 12:00 UTC",
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Fatalf(`Failed test: Got: %s, Want: it to contain %s`, sb.String(), want)
+		}
+	}
+}
+
+func TestWriteMarkdown_Unreadable(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	cols, _ := columns.Parse("branch,behind,error")
+	root := testTree()
+	locked := tree.NewNode("locked", "/dev/locked", root)
+	locked.Error = "can't read directory: permission denied"
+	root.Children = append(root.Children, locked)
+
+	var sb strings.Builder
+	if err := WriteMarkdown(&sb, root, Options{Columns: cols, Flat: true}, now); err != nil {
+		t.Fatal(err)
+	}
+	want := "| Path | Branch | Behind | Error |\n| --- | --- | --- | --- |\n| libs/a\\|b | main | 2 |  |\n| locked |  |  | can't read directory: permission denied |\n"
+	if sb.String() != want {
+		t.Fatalf(`Failed test: Got: %q, Want: %q`, sb.String(), want)
+	}
+}
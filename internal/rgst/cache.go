@@ -0,0 +1,65 @@
+package rgst
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/jobodd/rgst/internal/cache"
+)
+
+// nil when the cache is turned off
+var statsCache *cache.Cache
+
+func loadCache() *cache.Cache {
+	path, err := cache.DefaultPath()
+	if err != nil {
+		return nil
+	}
+	return cache.Load(path)
+}
+
+// a cache that can't be written only costs speed, so it isn't fatal
+func saveCache() {
+	if statsCache == nil {
+		return
+	}
+	statsCache.Prune()
+	if err := statsCache.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't save the stats cache: %s\n", err)
+	}
+}
+
+// KnownRepos lists the cached repos under dir, relative to it, for shell
+// completion
+func KnownRepos(dir string) []string {
+	c := loadCache()
+	if c == nil {
+		return nil
+	}
+	var repos []string
+	for _, repo := range c.Repos(dir) {
+		if rel, err := filepath.Rel(dir, repo); err == nil && rel != "." {
+			repos = append(repos, rel)
+		}
+	}
+	return repos
+}
+
+// KnownBranches lists the branches the cached repos under dir are on
+func KnownBranches(dir string) []string {
+	c := loadCache()
+	if c == nil {
+		return nil
+	}
+	var branches []string
+	for _, repo := range c.Repos(dir) {
+		entry, _ := c.Stale(repo)
+		if branch := entry.GitStats.CurrentBranch; branch != "" && !slices.Contains(branches, branch) {
+			branches = append(branches, branch)
+		}
+	}
+	slices.Sort(branches)
+	return branches
+}
@@ -0,0 +1,126 @@
+package rgst
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jobodd/rgst/internal/colours"
+	"github.com/jobodd/rgst/internal/plural"
+	"github.com/jobodd/rgst/internal/policy"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+var ErrCheckFailed = errors.New("one or more repositories failed the check")
+
+func Check(opts Options) error {
+	if err := loadRules(&opts); err != nil {
+		return err
+	}
+	rules := append(opts.CheckOptions.Rules(), opts.Rules...)
+	if len(rules) == 0 {
+		return errors.New("No checks given. (See rgst check --help for flags, or --rules for a rules file)")
+	}
+
+	node := discoverRepos(opts)
+	if node == nil {
+		return nil
+	}
+
+	if !opts.NoCache {
+		statsCache = loadCache()
+		defer saveCache()
+	}
+
+	p := newProgress(opts, "collecting stats", node)
+	collectGitStats(node, opts, p)
+	p.Stop()
+
+	counts := policy.Evaluate(node, rules)
+	printViolations(os.Stdout, node)
+
+	if counts[policy.SeverityError] > 0 {
+		return ErrCheckFailed
+	}
+	return nil
+}
+
+func printViolations(out io.Writer, root *t.Node) {
+	repos, failed, unreadable := 0, 0, 0
+	t.Walk(root, func(n *t.Node) {
+		for _, v := range n.Violations {
+			fmt.Fprintf(out, "%s %s %s\n", colouredSeverity(v.Severity), displayPath(n), v.Message)
+		}
+		switch {
+		case !n.IsGitRepo && n.Error != "":
+			unreadable++
+		case n.IsGitRepo:
+			repos++
+			if len(n.Violations) > 0 {
+				failed++
+			}
+		}
+	})
+	fmt.Fprintf(out, "%d of %d repos failed the check\n", failed, repos)
+	printUnreadable(out, unreadable)
+}
+
+func loadRules(opts *Options) error {
+	if opts.RulesFile == "" {
+		return nil
+	}
+	rules, err := policy.LoadRules(opts.RulesFile)
+	if err != nil {
+		return err
+	}
+	opts.Rules = rules
+	return nil
+}
+
+func printPolicySummary(out io.Writer, root *t.Node) {
+	repos, failing, unreadable := 0, 0, 0
+	counts := map[string]int{}
+	t.Walk(root, func(n *t.Node) {
+		if !n.IsGitRepo {
+			if n.Error != "" {
+				unreadable++
+			}
+			return
+		}
+		repos++
+		if len(n.Violations) > 0 {
+			failing++
+		}
+		for _, v := range n.Violations {
+			counts[v.Severity]++
+		}
+	})
+
+	if failing == 0 {
+		fmt.Fprintf(out, "\nPolicy: all %d repos pass\n", repos)
+	} else {
+		fmt.Fprintf(out, "\nPolicy: %s, %s in %d of %d repos\n",
+			colours.ColouredString(plural.Count(counts[policy.SeverityError], "error"), colours.Red),
+			colours.ColouredString(plural.Count(counts[policy.SeverityWarning], "warning"), colours.Yellow),
+			failing,
+			repos,
+		)
+	}
+	printUnreadable(out, unreadable)
+}
+
+func colouredSeverity(severity string) string {
+	if severity == policy.SeverityWarning {
+		return colours.ColouredString(severity+":", colours.Yellow)
+	}
+	return colours.ColouredString(severity+":", colours.Red)
+}
+
+// a repo at the scan root is better known by its name than "."
+func displayPath(n *t.Node) string {
+	if n.Parent == nil {
+		return n.FolderName
+	}
+	return n.RelPath()
+}
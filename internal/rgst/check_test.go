@@ -0,0 +1,49 @@
+package rgst
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jobodd/rgst/internal/colours"
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/policy"
+	"github.com/jobodd/rgst/internal/tree"
+)
+
+func checkTree() *tree.Node {
+	root := tree.NewNode("dev", "/dev", nil)
+	clean := tree.NewNode("clean", "/dev/clean", root)
+	clean.IsGitRepo = true
+	clean.GitStats = git.GitStats{ChangedFiles: []string{}}
+	dirty := tree.NewNode("dirty", "/dev/dirty", root)
+	dirty.IsGitRepo = true
+	dirty.GitStats = git.GitStats{ChangedFiles: []string{"[ M] go.mod"}}
+	locked := tree.NewNode("locked", "/dev/locked", root)
+	locked.Error = "can't read directory: permission denied"
+	root.Children = append(root.Children, clean, dirty, locked)
+	return root
+}
+
+func TestPrintViolations_CountsUnreadableApart(t *testing.T) {
+	colours.SetEnabled(false)
+	defer colours.SetEnabled(true)
+	root := checkTree()
+	policy.Evaluate(root, policy.CheckOptions{RequireClean: true, MaxBehind: -1}.Rules())
+
+	var sb strings.Builder
+	printViolations(&sb, root)
+	want := "error: dirty has 1 changed file\n" +
+		"error: locked can't read directory: permission denied\n" +
+		"1 of 2 repos failed the check\n" +
+		"1 directory couldn't be read\n"
+	if sb.String() != want {
+		t.Fatalf(`Failed test: Got: %q, Want: %q`, sb.String(), want)
+	}
+
+	sb.Reset()
+	printPolicySummary(&sb, root)
+	want = "\nPolicy: 1 error, 0 warnings in 1 of 2 repos\n1 directory couldn't be read\n"
+	if sb.String() != want {
+		t.Fatalf(`Failed test: Got: %q, Want: %q`, sb.String(), want)
+	}
+}
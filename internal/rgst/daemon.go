@@ -0,0 +1,157 @@
+package rgst
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+const (
+	queryTimeout = 5 * time.Second
+)
+
+type queryRequest struct {
+	Path string `json:"path"`
+}
+
+type queryResponse struct {
+	Root  *t.Node `json:"root,omitempty"`
+	Error string  `json:"error,omitempty"`
+}
+
+func DefaultSocketPath() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "rgst.sock")
+	}
+	// a directory of our own, as anyone can create files in the temp dir
+	return filepath.Join(os.TempDir(), fmt.Sprintf("rgst-%d", os.Getuid()), "rgst.sock")
+}
+
+func Daemon(opts Options) error {
+	// see watchRepos
+	os.Setenv("GIT_OPTIONAL_LOCKS", "0")
+
+	if !opts.NoCache {
+		statsCache = loadCache()
+		defer saveCache()
+	}
+
+	listener, err := listenSocket(opts.SocketPath)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	ws := &workspace{opts: opts}
+	ws.scan()
+	go ws.watch(ctx)
+
+	fmt.Fprintf(os.Stderr, "rgst daemon listening on %s\n", opts.SocketPath)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go ws.serve(conn)
+	}
+}
+
+func listenSocket(socketPath string) (net.Listener, error) {
+	dir := filepath.Dir(socketPath)
+	if err := os.Mkdir(dir, 0700); err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, err
+	}
+	if err := checkSocketDir(dir); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(socketPath); err == nil {
+		if conn, err := net.Dial("unix", socketPath); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("An rgst daemon is already listening on %s", socketPath)
+		}
+		// left behind by a daemon that didn't shut down cleanly
+		os.Remove(socketPath)
+	}
+
+	return listenUnix(socketPath)
+}
+
+func (ws *workspace) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(queryTimeout))
+
+	var req queryRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	ws.View(func(root *t.Node) {
+		var resp queryResponse
+		if root == nil {
+			resp.Error = "No git repositories found"
+		} else if resp.Root = findSubtree(root, req.Path); resp.Root == nil {
+			resp.Error = fmt.Sprintf("%s isn't watched by this daemon, which watches %s", req.Path, root.AbsPath)
+		}
+		json.NewEncoder(conn).Encode(resp)
+	})
+}
+
+// findSubtree returns the deepest node containing path
+func findSubtree(root *t.Node, path string) *t.Node {
+	var found *t.Node
+	t.Walk(root, func(n *t.Node) {
+		if path == n.AbsPath || strings.HasPrefix(path, n.AbsPath+string(filepath.Separator)) {
+			found = n
+		}
+	})
+	return found
+}
+
+func Query(opts Options) error {
+	absolutePath, err := filepath.Abs(opts.Path)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("unix", opts.SocketPath, queryTimeout)
+	if err != nil {
+		return fmt.Errorf("No rgst daemon is listening on %s. Start one with `rgst daemon`", opts.SocketPath)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(queryTimeout))
+
+	if err := json.NewEncoder(conn).Encode(queryRequest{Path: absolutePath}); err != nil {
+		return err
+	}
+	var resp queryResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+
+	t.LinkParents(resp.Root)
+	return printOutput(os.Stdout, resp.Root, opts)
+}
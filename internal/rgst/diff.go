@@ -0,0 +1,98 @@
+package rgst
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jobodd/rgst/internal/colours"
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/snapshot"
+	"github.com/jobodd/rgst/internal/table"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+func saveSnapshot(root *t.Node, opts Options) error {
+	s := snapshot.Take(root, opts.RecurseDepth, time.Now(), git.HeadCommit)
+	s.Filters = t.SaveFilters(opts.FilterOptions, opts.DiscoveryOptions)
+	if err := s.Save(opts.SnapshotPath); err != nil {
+		return fmt.Errorf("saving snapshot: %w", err)
+	}
+	return nil
+}
+
+// restoreFilters sets up opts to discover the same repos as an earlier
+// scan
+func restoreFilters(opts *Options, f t.SavedFilters) (err error) {
+	opts.FilterOptions, opts.DiscoveryOptions, err = f.Restore()
+	return err
+}
+
+// Diff compares a snapshot with a later one, or with the workspace as it
+// is now. The workspace is rescanned with the snapshot's own root, depth
+// and filters, so the two cover the same repos.
+func Diff(opts Options, fromPath string, toPath string) error {
+	from, err := snapshot.Load(fromPath)
+	if err != nil {
+		return err
+	}
+
+	var to snapshot.Snapshot
+	if toPath != "" {
+		to, err = snapshot.Load(toPath)
+		if err != nil {
+			return err
+		}
+	} else {
+		scanOpts := opts
+		scanOpts.Path = from.Root
+		scanOpts.RecurseDepth = from.Depth
+		if err := restoreFilters(&scanOpts, from.Filters); err != nil {
+			return fmt.Errorf("the snapshot's filters: %w", err)
+		}
+		if _, err := os.Stat(from.Root); err != nil {
+			return fmt.Errorf("can't rescan the snapshot's root: %w", err)
+		}
+
+		if !opts.NoCache {
+			statsCache = loadCache()
+			defer saveCache()
+		}
+		root := discoverRepos(scanOpts)
+		p := newProgress(opts, "collecting stats", root)
+		collectGitStats(root, scanOpts, p)
+		p.Stop()
+		to = snapshot.Take(root, from.Depth, time.Now(), git.HeadCommit)
+		to.Root = from.Root
+	}
+
+	changes := snapshot.Diff(from, to, git.CountCommits)
+	if opts.Format == FormatJSON {
+		return printJSON(os.Stdout, changes)
+	}
+	return printChanges(os.Stdout, from, to, changes)
+}
+
+func printChanges(out io.Writer, from snapshot.Snapshot, to snapshot.Snapshot, changes []snapshot.Change) error {
+	since := from.TakenAt.Local().Format("2006-01-02 15:04")
+	if len(changes) == 0 {
+		fmt.Fprintf(out, "No changes in %d repos since %s\n", len(to.Repos), since)
+		return nil
+	}
+
+	fmt.Fprintf(out, "Since %s (%d repos, now %d):\n", since, len(from.Repos), len(to.Repos))
+	w := table.NewWriter(out, 2)
+	for _, c := range changes {
+		switch c.Kind {
+		case snapshot.KindAppeared:
+			fmt.Fprintf(w, "%s %s\t%s\n", colours.ColouredString("+", colours.Green), c.Path, c.Kind)
+		case snapshot.KindDisappeared:
+			fmt.Fprintf(w, "%s %s\t%s\n", colours.ColouredString("-", colours.Red), c.Path, c.Kind)
+		default:
+			fmt.Fprintf(w, "%s %s\t%s\n", colours.ColouredString("~", colours.Yellow), c.Path, strings.Join(c.Details, ", "))
+		}
+	}
+	return w.Flush()
+}
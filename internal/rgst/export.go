@@ -0,0 +1,74 @@
+package rgst
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jobodd/rgst/internal/columns"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+// printDelimited writes a header row and a row per repo or unreadable
+// directory, as CSV or as TSV. TSV fields can't hold tabs or newlines, so
+// they're replaced with spaces rather than quoted. CSV is for opening in
+// spreadsheets, so formulas are escaped there; TSV is for other programs,
+// which get the values as they are.
+func printDelimited(out io.Writer, root *t.Node, cols []columns.Column, format string) error {
+	now := time.Now()
+	w := csv.NewWriter(out)
+	writeRow := func(fields []string) error {
+		for i, field := range fields {
+			fields[i] = escapeFormula(field)
+		}
+		return w.Write(fields)
+	}
+	if format == FormatTSV {
+		writeRow = func(fields []string) error {
+			for i, field := range fields {
+				fields[i] = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(field)
+			}
+			_, err := io.WriteString(out, strings.Join(fields, "\t")+"\n")
+			return err
+		}
+	}
+
+	var header []string
+	for _, col := range cols {
+		header = append(header, col.Header)
+	}
+	if err := writeRow(header); err != nil {
+		return err
+	}
+
+	var err error
+	t.Walk(root, func(n *t.Node) {
+		if !n.IsGitRepo && n.Error == "" || err != nil {
+			return
+		}
+		var row []string
+		for _, col := range cols {
+			value := ""
+			if col.Describes(n) {
+				value = col.Value(n, now)
+			}
+			row = append(row, value)
+		}
+		err = writeRow(row)
+	})
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// spreadsheets run cells starting with these as formulas, and branch and
+// directory names can start with any of them
+func escapeFormula(field string) string {
+	if field != "" && strings.ContainsRune("=+-@\t\r", rune(field[0])) {
+		return "'" + field
+	}
+	return field
+}
@@ -0,0 +1,79 @@
+package rgst
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jobodd/rgst/internal/columns"
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/tree"
+)
+
+func exportTree() *tree.Node {
+	root := tree.NewNode("dev", "/dev", nil)
+	for _, repo := range []struct {
+		name     string
+		gitStats git.GitStats
+		err      string
+	}{
+		{"a,b", git.GitStats{CurrentBranch: `say "hi"`, CommitsBehindRemote: 2}, ""},
+		{"-rf", git.GitStats{CurrentBranch: "=HYPERLINK(1)", CommitsBehindRemote: -1}, ""},
+		{"broken", git.GitStats{}, "fatal: bad\tindex\nline two"},
+	} {
+		n := tree.NewNode(repo.name, "/dev/"+repo.name, root)
+		n.IsGitRepo = true
+		n.GitStats = repo.gitStats
+		n.Error = repo.err
+		root.Children = append(root.Children, n)
+	}
+	return root
+}
+
+func TestPrintDelimited_CSV(t *testing.T) {
+	cols, _ := columns.Parse("path,branch,behind,error")
+	var sb strings.Builder
+	if err := printDelimited(&sb, exportTree(), cols, FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+	want := "Path,Branch,Behind,Error\n" +
+		"\"a,b\",\"say \"\"hi\"\"\",2,\n" +
+		"'-rf,'=HYPERLINK(1),,\n" +
+		"broken,,,\"fatal: bad\tindex\nline two\"\n"
+	if sb.String() != want {
+		t.Fatalf(`Failed test: Got: %q, Want: %q`, sb.String(), want)
+	}
+}
+
+func TestPrintDelimited_TSV(t *testing.T) {
+	cols, _ := columns.Parse("path,branch,behind,error")
+	var sb strings.Builder
+	if err := printDelimited(&sb, exportTree(), cols, FormatTSV); err != nil {
+		t.Fatal(err)
+	}
+	want := "Path\tBranch\tBehind\tError\n" +
+		"a,b\tsay \"hi\"\t2\t\n" +
+		"-rf\t=HYPERLINK(1)\t\t\n" +
+		"broken\t\t\tfatal: bad index line two\n"
+	if sb.String() != want {
+		t.Fatalf(`Failed test: Got: %q, Want: %q`, sb.String(), want)
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	cases := map[string]string{
+		"":          "",
+		"main":      "main",
+		"=1+1":      "'=1+1",
+		"+1":        "'+1",
+		"-1":        "'-1",
+		"@SUM(A1)":  "'@SUM(A1)",
+		"\t=1+1":    "'\t=1+1",
+		"\r=1+1":    "'\r=1+1",
+		"feature-x": "feature-x",
+	}
+	for field, want := range cases {
+		if got := escapeFormula(field); got != want {
+			t.Fatalf(`Failed test: Got: %q, Want: %q`, got, want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package rgst
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	FormatText       = "text"
+	FormatJSON       = "json"
+	FormatPrometheus = "prometheus"
+	FormatCSV        = "csv"
+	FormatTSV        = "tsv"
+	FormatMarkdown   = "markdown"
+	FormatHTML       = "html"
+)
+
+var Formats = []string{FormatText, FormatJSON, FormatPrometheus, FormatCSV, FormatTSV, FormatMarkdown, FormatHTML}
+
+func CheckFormat(format string) error {
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown format %q. Expected one of: %v", format, Formats)
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
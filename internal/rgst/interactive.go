@@ -0,0 +1,33 @@
+package rgst
+
+import (
+	"bytes"
+	"strings"
+
+	t "github.com/jobodd/rgst/internal/tree"
+	"github.com/jobodd/rgst/internal/tui"
+)
+
+func browseRepos(root *t.Node, opts Options) error {
+	// file lists are shown by expanding a repo instead, which keeps the
+	// rendered tree at one line per node
+	lineOpts := opts
+	lineOpts.GitOptions.ShowFiles = false
+	lineOpts.Rules = nil
+	lineOpts.Flat = false
+	lineOpts.Template = nil
+
+	return tui.Run(root, tui.Options{
+		GitOptions: opts.GitOptions,
+		Lines: func(root *t.Node) []string {
+			var buf bytes.Buffer
+			printOutput(&buf, root, lineOpts)
+			return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		},
+		Refresh: func(n *t.Node) error {
+			gitStats, err := gitStatsFor(n, opts)
+			setGitStats(n, gitStats, err)
+			return err
+		},
+	})
+}
@@ -0,0 +1,287 @@
+package rgst
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jobodd/rgst/internal/colours"
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/plural"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+const (
+	PromptPlain = "plain"
+	PromptBash  = "bash"
+	PromptZsh   = "zsh"
+	PromptTmux  = "tmux"
+)
+
+var PromptStyles = []string{PromptPlain, PromptBash, PromptZsh, PromptTmux}
+
+func CheckPromptStyle(style string) error {
+	for _, s := range PromptStyles {
+		if s == style {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown prompt style %q. Expected one of: %v", style, PromptStyles)
+}
+
+const DefaultPromptBudget = 150 * time.Millisecond
+
+// how many repos a prompt checks at once
+const promptWorkers = 4
+
+type promptCounts struct {
+	Repos  int
+	Dirty  int
+	Behind int
+	Ahead  int
+	// some repos weren't checked within the budget, so their last cached
+	// stats were counted
+	Stale bool
+}
+
+// Prompt prints a one line summary of the workspace, e.g.
+// "12 repos, 3 dirty, 2 behind", for shell prompts and status lines.
+// Without a path the workspace is the deepest directory rgst has been run
+// on that contains the current directory. It never takes much longer than
+// opts.PromptBudget: repos that aren't checked in time use their cached
+// stats, however old, and the summary is marked with "~".
+func Prompt(opts Options) error {
+	if opts.PromptStyle == "" {
+		opts.PromptStyle = PromptPlain
+	}
+	if opts.PromptBudget == 0 {
+		opts.PromptBudget = DefaultPromptBudget
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.PromptBudget)
+	defer cancel()
+
+	// see watchRepos
+	os.Setenv("GIT_OPTIONAL_LOCKS", "0")
+
+	statsCache = loadCache()
+	if statsCache == nil {
+		return nil
+	}
+
+	opts, err := promptWorkspace(opts)
+	if err != nil {
+		return err
+	}
+
+	discovered, fresh := checkPromptRepos(ctx, opts)
+	repos := discovered
+	if repos == nil {
+		repos = cachedRepos(opts.Path, opts)
+	}
+	counts := countPrompt(repos, fresh)
+
+	// whatever was checked in time makes the next prompt quicker. Pruning
+	// would stat every cached repo, so it's left to full runs, and a
+	// prompt has nowhere to show a warning.
+	statsCache.Save()
+
+	fmt.Println(formatPrompt(counts, opts.PromptStyle))
+	return nil
+}
+
+// checkPromptRepos discovers the workspace's repos and collects their
+// stats until ctx is done. discovered is nil if discovery didn't finish.
+// Git is killed at the deadline, and waited for, so nothing is left
+// running once the prompt has printed.
+func checkPromptRepos(ctx context.Context, opts Options) (discovered []string, fresh map[string]git.GitStats) {
+	var mu sync.Mutex
+	fresh = map[string]git.GitStats{}
+	var running sync.WaitGroup
+
+	jobs := make(chan string)
+	go func() {
+		defer close(jobs)
+		var repos []string
+		t.Walk(discoverRepos(opts), func(n *t.Node) {
+			if n.IsGitRepo {
+				repos = append(repos, n.AbsPath)
+			}
+		})
+		mu.Lock()
+		discovered = append([]string{}, repos...)
+		mu.Unlock()
+
+		for _, repo := range repos {
+			select {
+			case jobs <- repo:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var workers sync.WaitGroup
+	for i := 0; i < promptWorkers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for repo := range jobs {
+				// nothing starts once the deadline has passed, so the
+				// Wait below can't miss a check
+				mu.Lock()
+				if ctx.Err() != nil {
+					mu.Unlock()
+					return
+				}
+				running.Add(1)
+				mu.Unlock()
+
+				gitStats, err := gitStatsContext(ctx, repo, opts)
+				if err == nil {
+					mu.Lock()
+					fresh[repo] = gitStats
+					mu.Unlock()
+				}
+				running.Done()
+			}
+		}()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		// taking the lock makes sure every check that started has been
+		// counted. Discovery may still be walking, but it runs no git.
+		mu.Lock()
+		mu.Unlock()
+		running.Wait()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return discovered, fresh
+}
+
+// promptWorkspace finds the workspace, and sets opts up to search it with
+// the depth and filters it was last scanned with. A directory rgst hasn't
+// been run on is searched with the prompt's own options.
+func promptWorkspace(opts Options) (Options, error) {
+	explicit := opts.Path != ""
+	dir, err := os.Getwd()
+	if explicit {
+		dir, err = filepath.Abs(opts.Path)
+	}
+	if err != nil {
+		return opts, err
+	}
+	opts.Path = dir
+
+	root, ok := statsCache.Workspace(dir)
+	// a path given explicitly is the workspace, rather than a part of one
+	if !ok || explicit && root.Path != dir {
+		return opts, nil
+	}
+	opts.Path = root.Path
+	opts.RecurseDepth = root.Depth
+	if err := restoreFilters(&opts, root.Filters); err != nil {
+		return opts, fmt.Errorf("the filters %s was scanned with: %w", root.Path, err)
+	}
+	return opts, nil
+}
+
+// cachedRepos stands in for discovery when it doesn't finish in time,
+// keeping to the same depth and filters
+func cachedRepos(root string, opts Options) []string {
+	var repos []string
+	for _, repo := range statsCache.Repos(root) {
+		rel, _ := filepath.Rel(root, repo)
+		depth := uint(strings.Count(rel, string(filepath.Separator)))
+		if rel != "." {
+			depth++
+		}
+		if depth <= opts.RecurseDepth+1 && opts.FilterOptions.Keep(rel, repo) {
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
+
+func countPrompt(repos []string, fresh map[string]git.GitStats) promptCounts {
+	var counts promptCounts
+	for _, repo := range repos {
+		counts.Repos++
+		gitStats, ok := fresh[repo]
+		if !ok {
+			counts.Stale = true
+			entry, ok := statsCache.Stale(repo)
+			if !ok {
+				continue
+			}
+			gitStats = entry.GitStats
+		}
+		if gitStats.DirtyCount() > 0 {
+			counts.Dirty++
+		}
+		if gitStats.CommitsBehindRemote > 0 {
+			counts.Behind++
+		}
+		if gitStats.CommitsAheadOfRemote > 0 {
+			counts.Ahead++
+		}
+	}
+	return counts
+}
+
+// formatPrompt leaves out counts of zero, and prints nothing outside a
+// workspace so the segment disappears
+func formatPrompt(counts promptCounts, style string) string {
+	if counts.Repos == 0 {
+		return ""
+	}
+
+	parts := []string{plural.Count(counts.Repos, "repo")}
+	if counts.Dirty > 0 {
+		parts = append(parts, promptColour(fmt.Sprintf("%d dirty", counts.Dirty), "yellow", style))
+	}
+	if counts.Behind > 0 {
+		parts = append(parts, promptColour(fmt.Sprintf("%d behind", counts.Behind), "red", style))
+	}
+	if counts.Ahead > 0 {
+		parts = append(parts, promptColour(fmt.Sprintf("%d ahead", counts.Ahead), "green", style))
+	}
+
+	s := strings.Join(parts, ", ")
+	if counts.Stale {
+		s = "~" + s
+	}
+	return s
+}
+
+var promptANSI = map[string]string{
+	"red":    colours.Red,
+	"green":  colours.Green,
+	"yellow": colours.Yellow,
+}
+
+// promptColour uses each shell's own markup for zero-width sequences, so
+// line editing isn't thrown off by the escape codes
+func promptColour(s string, colour string, style string) string {
+	switch style {
+	case PromptBash:
+		return "\001" + promptANSI[colour] + "\002" + s + "\001" + colours.Reset + "\002"
+	case PromptZsh:
+		return "%F{" + colour + "}" + s + "%f"
+	case PromptTmux:
+		return "#[fg=" + colour + "]" + s + "#[default]"
+	}
+	return s
+}
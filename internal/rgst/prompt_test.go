@@ -0,0 +1,127 @@
+package rgst
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jobodd/rgst/internal/cache"
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/tree"
+)
+
+func TestFormatPrompt(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts promptCounts
+		style  string
+		want   string
+	}{
+		{"outside a workspace", promptCounts{}, PromptBash, ""},
+		{"zero counts left out", promptCounts{Repos: 3}, PromptPlain, "3 repos"},
+		{"plain", promptCounts{Repos: 12, Dirty: 3, Behind: 2, Ahead: 1}, PromptPlain, "12 repos, 3 dirty, 2 behind, 1 ahead"},
+		{"stale", promptCounts{Repos: 1, Behind: 1, Stale: true}, PromptPlain, "~1 repo, 1 behind"},
+		{"bash", promptCounts{Repos: 2, Dirty: 1}, PromptBash, "2 repos, \001\033[33m\0021 dirty\001\033[0m\002"},
+		{"zsh", promptCounts{Repos: 2, Behind: 1}, PromptZsh, "2 repos, %F{red}1 behind%f"},
+		{"tmux", promptCounts{Repos: 2, Ahead: 2}, PromptTmux, "2 repos, #[fg=green]2 ahead#[default]"},
+	}
+	for _, tt := range tests {
+		if got := formatPrompt(tt.counts, tt.style); got != tt.want {
+			t.Fatalf(`Failed test %s: Got: %q, Want: %q`, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPromptColour(t *testing.T) {
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{PromptPlain, "1 dirty"},
+		{PromptBash, "\001\033[33m\0021 dirty\001\033[0m\002"},
+		{PromptZsh, "%F{yellow}1 dirty%f"},
+		{PromptTmux, "#[fg=yellow]1 dirty#[default]"},
+	}
+	for _, tt := range tests {
+		if got := promptColour("1 dirty", "yellow", tt.style); got != tt.want {
+			t.Fatalf(`Failed test %s: Got: %q, Want: %q`, tt.style, got, tt.want)
+		}
+	}
+}
+
+// seedCache points statsCache at a fresh cache for the test's duration
+func seedCache(t *testing.T, entries map[string]git.GitStats) {
+	statsCache = cache.Load(filepath.Join(t.TempDir(), "stats.json"))
+	t.Cleanup(func() { statsCache = nil })
+	for repo, gitStats := range entries {
+		statsCache.Put(repo, cache.Fingerprint{}, false, gitStats)
+	}
+}
+
+func TestCachedRepos(t *testing.T) {
+	seedCache(t, map[string]git.GitStats{
+		"/dev":                 {},
+		"/dev/api":             {},
+		"/dev/team/web":        {},
+		"/dev/team/deep/store": {},
+		"/dev/vendor/lib":      {},
+		"/other/repo":          {},
+	})
+	exclude, _ := tree.ParsePattern("vendor/**")
+	opts := Options{RecurseDepth: 1, FilterOptions: tree.FilterOptions{Exclude: []tree.Pattern{exclude}}}
+
+	got := cachedRepos("/dev", opts)
+	want := []string{"/dev", "/dev/api", "/dev/team/web"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
+
+func TestCountPrompt(t *testing.T) {
+	seedCache(t, map[string]git.GitStats{
+		"/dev/api": {CommitsBehindRemote: 2, FilesModifiedCount: 1, ChangedFiles: []string{"[ M] go.mod"}},
+		"/dev/web": {CommitsAheadOfRemote: 1},
+	})
+	repos := []string{"/dev/api", "/dev/web", "/dev/new"}
+
+	// every repo checked in time, whatever the cache says
+	fresh := map[string]git.GitStats{
+		"/dev/api": {},
+		"/dev/web": {},
+		"/dev/new": {CommitsBehindRemote: 1},
+	}
+	got := countPrompt(repos, fresh)
+	want := promptCounts{Repos: 3, Behind: 1}
+	if got != want {
+		t.Fatalf(`Failed test: Got: %+v, Want: %+v`, got, want)
+	}
+
+	// none checked in time falls back to the cache, and a repo that was
+	// never cached is still counted
+	got = countPrompt(repos, map[string]git.GitStats{})
+	want = promptCounts{Repos: 3, Dirty: 1, Behind: 1, Ahead: 1, Stale: true}
+	if got != want {
+		t.Fatalf(`Failed test: Got: %+v, Want: %+v`, got, want)
+	}
+}
+
+func TestPromptWorkspace_UsesSavedScan(t *testing.T) {
+	seedCache(t, nil)
+	root := t.TempDir()
+	statsCache.AddRoot(cache.Root{Path: root, Depth: 3, Filters: tree.SavedFilters{Exclude: []string{"vendor/**"}}})
+
+	opts, err := promptWorkspace(Options{Path: root})
+	if err != nil {
+		t.Fatalf("Failed test with error: %s", err)
+	}
+	if opts.RecurseDepth != 3 || len(opts.FilterOptions.Exclude) != 1 {
+		t.Fatalf(`Failed test: Got depth %d and %v, Want depth 3 excluding vendor/**`, opts.RecurseDepth, opts.FilterOptions.Exclude)
+	}
+
+	// a directory that was never scanned keeps the prompt's own options
+	other := t.TempDir()
+	opts, _ = promptWorkspace(Options{Path: other, RecurseDepth: 1})
+	if opts.Path != other || opts.RecurseDepth != 1 {
+		t.Fatalf(`Failed test: Got: %s at depth %d, Want: %s at depth 1`, opts.Path, opts.RecurseDepth, other)
+	}
+}
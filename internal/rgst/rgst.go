@@ -1,28 +1,136 @@
 package rgst
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
-	"text/tabwriter"
+	"text/template"
 
+	"github.com/jobodd/rgst/internal/cache"
+	"github.com/jobodd/rgst/internal/colours"
+	"github.com/jobodd/rgst/internal/columns"
 	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/metrics"
+	"github.com/jobodd/rgst/internal/policy"
+	"github.com/jobodd/rgst/internal/progress"
+	"github.com/jobodd/rgst/internal/report"
+	"github.com/jobodd/rgst/internal/symbols"
+	"github.com/jobodd/rgst/internal/table"
+	"github.com/jobodd/rgst/internal/term"
 	t "github.com/jobodd/rgst/internal/tree"
 )
 
 type Options struct {
-	Path          string
-	RecurseDepth  uint
-	GitOptions    git.GitOptions
-	FilterOptions t.FilterOptions
+	Path             string
+	RecurseDepth     uint
+	Format           string
+	NoProgress       bool
+	Summary          bool
+	Watch            bool
+	Interactive      bool
+	NoCache          bool
+	SocketPath       string
+	ListenAddr       string
+	PollInterval     time.Duration
+	Colour           string
+	Theme            string
+	Symbols          string
+	Flat             bool
+	GitOptions       git.GitOptions
+	FilterOptions    t.FilterOptions
+	DiscoveryOptions t.DiscoveryOptions
+	CheckOptions     policy.CheckOptions
+	RulesFile        string
+	SnapshotPath     string
+	PromptStyle      string
+	PromptBudget     time.Duration
+	Columns          []columns.Column
+	Template         *template.Template
+	Rules            []policy.Rule
 }
 
+var ErrUpdateFailed = errors.New("one or more repositories failed to update")
+
 func MainProcess(opts Options) error {
+	if err := loadRules(&opts); err != nil {
+		return err
+	}
+
+	var phases []phase
+	timePhase := func(name string, start time.Time) {
+		phases = append(phases, phase{name, time.Since(start)})
+	}
+
+	start := time.Now()
+	node := discoverRepos(opts)
+	if node == nil {
+		return nil
+	}
+	timePhase("discovery", start)
+
+	if !opts.NoCache {
+		statsCache = loadCache()
+		if statsCache != nil {
+			statsCache.AddRoot(cache.Root{
+				Path:    node.AbsPath,
+				Depth:   opts.RecurseDepth,
+				Filters: t.SaveFilters(opts.FilterOptions, opts.DiscoveryOptions),
+			})
+		}
+		defer saveCache()
+	}
+
+	if opts.GitOptions.ShouldUpdate() {
+		start = time.Now()
+		p := newProgress(opts, updateLabel(opts.GitOptions), node)
+		updateGitRepos(node, opts.GitOptions, p)
+		p.Stop()
+		timePhase(updateLabel(opts.GitOptions), start)
+	}
+
+	// update the git stats for each directory
+	start = time.Now()
+	p := newProgress(opts, "collecting stats", node)
+	collectGitStats(node, opts, p)
+	p.Stop()
+	timePhase("stats", start)
+
+	if opts.SnapshotPath != "" {
+		if err := saveSnapshot(node, opts); err != nil {
+			return err
+		}
+	}
+
+	if opts.Watch {
+		return watchRepos(node, opts)
+	}
+
+	if opts.Interactive {
+		return browseRepos(node, opts)
+	}
+
+	if err := printOutput(os.Stdout, node, opts); err != nil {
+		return err
+	}
+	if opts.Summary {
+		printSummary(os.Stdout, node, phases)
+	}
+
+	if anyUpdateFailed(node) {
+		return ErrUpdateFailed
+	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.TabIndent)
+	return nil
+}
+
+func discoverRepos(opts Options) *t.Node {
 	maxDirLength := 0
 
 	// figure out the base path
@@ -34,28 +142,78 @@ func MainProcess(opts Options) error {
 
 	// create the directory node structure
 	node := t.NewNode(targetDir, absolutePath, nil)
-	t.GetGitDirectories(node, 0, opts.RecurseDepth, &maxDirLength)
-	if t.FilterNodes(node, opts.FilterOptions) == nil {
-		return nil
+	t.GetGitDirectories(node, 0, opts.RecurseDepth, &maxDirLength, opts.DiscoveryOptions)
+	return t.FilterNodes(node, opts.FilterOptions)
+}
+
+func printOutput(out io.Writer, node *t.Node, opts Options) error {
+	// evaluated at every print, as watch and interactive modes print again
+	// after stats change
+	if len(opts.Rules) > 0 {
+		policy.Evaluate(node, opts.Rules)
 	}
 
-	if opts.GitOptions.ShouldFetch || opts.GitOptions.ShouldFetchAll || opts.GitOptions.ShouldPull {
-		updateGitRepos(node, opts.GitOptions)
+	switch opts.Format {
+	case FormatJSON:
+		return printJSON(out, node)
+	case FormatPrometheus:
+		return metrics.WritePrometheus(out, node, time.Now())
+	case FormatCSV, FormatTSV:
+		cols := opts.Columns
+		if len(cols) == 0 {
+			cols = columns.All(opts.GitOptions.ShowMergeBase)
+		}
+		return printDelimited(out, node, cols, opts.Format)
+	case FormatMarkdown, FormatHTML:
+		reportOpts := report.Options{Columns: opts.Columns, Flat: opts.Flat, ShowFiles: opts.GitOptions.ShowFiles}
+		if len(reportOpts.Columns) == 0 {
+			reportOpts.Columns = columns.Default(opts.GitOptions.ShowMergeBase)
+			if opts.GitOptions.ShouldUpdate() {
+				update, _ := columns.Parse("update")
+				reportOpts.Columns = append(reportOpts.Columns, update...)
+			}
+		}
+		if opts.Format == FormatHTML {
+			return report.WriteHTML(out, node, reportOpts, time.Now())
+		}
+		return report.WriteMarkdown(out, node, reportOpts, time.Now())
+	default:
+		if opts.Template != nil {
+			return printTemplate(out, node, opts.Template)
+		}
+
+		w := table.NewWriter(out, 1)
+		cols := opts.Columns
+		if len(cols) == 0 {
+			cols = columns.Default(opts.GitOptions.ShowMergeBase)
+		}
+		printDirTree(w, node, opts.GitOptions, cols, opts.Flat)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if len(opts.Rules) > 0 {
+			printPolicySummary(out, node)
+		}
+		return nil
 	}
+}
 
-	// update the git stats for each directory
-	collectGitStats(node, opts.GitOptions)
-	folderTabCount := 8
-	if opts.GitOptions.ShowMergeBase {
-		folderTabCount += 2
+// progress is drawn on stderr so it never ends up in piped output
+func newProgress(opts Options, label string, root *t.Node) *progress.Tracker {
+	if opts.NoProgress || !term.IsTerminal(os.Stderr) {
+		return nil
 	}
-	printDirTree(w, node, opts.GitOptions, folderTabCount)
-	w.Flush()
+	return progress.New(os.Stderr, term.Width(os.Stderr), label, t.CountGitRepos(root))
+}
 
-	return nil
+func updateLabel(gitOpts git.GitOptions) string {
+	if gitOpts.ShouldPull {
+		return "pulling"
+	}
+	return "fetching"
 }
 
-func updateGitRepos(root *t.Node, gitOptions git.GitOptions) {
+func updateGitRepos(root *t.Node, gitOptions git.GitOptions, p *progress.Tracker) {
 	var wg sync.WaitGroup
 
 	t.Walk(root, func(n *t.Node) {
@@ -63,50 +221,145 @@ func updateGitRepos(root *t.Node, gitOptions git.GitOptions) {
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				git.UpdateDirectory(n.AbsPath, gitOptions)
+				p.Start(n.RelPath())
+				n.UpdateResult = git.UpdateDirectory(n.AbsPath, gitOptions)
+				p.Done(n.RelPath())
 			}()
 		}
 	})
 	wg.Wait()
 }
 
-func collectGitStats(root *t.Node, gitOpts git.GitOptions) {
+func anyUpdateFailed(root *t.Node) bool {
+	failed := false
+	t.Walk(root, func(n *t.Node) {
+		if n.UpdateResult.Failed() {
+			failed = true
+		}
+	})
+	return failed
+}
+
+func gitStatsFor(n *t.Node, opts Options) (git.GitStats, error) {
+	return gitStatsContext(context.Background(), n.AbsPath, opts)
+}
+
+func gitStatsContext(ctx context.Context, repo string, opts Options) (git.GitStats, error) {
+	if statsCache == nil {
+		return git.GetGitStatsContext(ctx, repo, opts.GitOptions)
+	}
+
+	fp, err := cache.FingerprintRepo(repo)
+	if err != nil {
+		return git.GetGitStatsContext(ctx, repo, opts.GitOptions)
+	}
+	if gitStats, ok := statsCache.Get(repo, fp, opts.GitOptions.ShowMergeBase); ok {
+		return gitStats, nil
+	}
+
+	gitStats, err := git.GetGitStatsContext(ctx, repo, opts.GitOptions)
+	if err != nil {
+		return gitStats, err
+	}
+	statsCache.Put(repo, fp, opts.GitOptions.ShowMergeBase, gitStats)
+	return gitStats, nil
+}
+
+// a repo git can't read keeps its error on the node rather than ending
+// the run
+func setGitStats(n *t.Node, gitStats git.GitStats, err error) {
+	n.GitStats = gitStats
+	n.Error = ""
+	if err != nil {
+		n.Error = err.Error()
+	}
+}
+
+func collectGitStats(root *t.Node, opts Options, p *progress.Tracker) {
 	t.Walk(root, func(n *t.Node) {
 		n.FolderTreeWidth = len(n.FolderName) + 4 + (n.GetDepth() * 2)
 
 		if n.IsGitRepo {
-			gitStats, err := git.GetGitStats(n.AbsPath, gitOpts)
-			if err != nil {
-				panic(err)
-			}
-			n.GitStats = gitStats
-
+			p.Start(n.RelPath())
+			gitStats, err := gitStatsFor(n, opts)
+			setGitStats(n, gitStats, err)
+			p.Done(n.RelPath())
 		}
 	})
 }
 
-func printDirTree(w *tabwriter.Writer, root *t.Node, gitOpts git.GitOptions, folderTabCount int) {
+// printDirTree draws the tree, or with flat a line per repo with its
+// path from the root
+func printDirTree(w *table.Writer, root *t.Node, gitOpts git.GitOptions, cols []columns.Column, flat bool) {
+	now := time.Now()
+	folderTabCount := len(cols) + 1
+
+	sym := symbols.Current
+
 	t.Walk(root, func(n *t.Node) {
-		leftPad := strings.Repeat("  ", n.GetDepth())
-		folderTreeText := fmt.Sprintf("%s|-- %s", leftPad, n.FolderName)
-		commitStats := git.PrettyGitStats(n.GitStats, gitOpts)
+		if flat && !n.IsGitRepo && n.Error == "" {
+			return
+		}
 
-		var line string
+		prefix, childPrefix := treePrefixes(n, sym)
+		icon := sym.Dir
 		if n.IsGitRepo {
-			line = fmt.Sprintf("%s\t%s\t%s", folderTreeText, n.GitStats.CurrentBranch, commitStats)
+			icon = sym.Repo
+		}
+		folderTreeText := prefix + icon + n.FolderName
+		if flat {
+			folderTreeText = icon + n.RelPath()
+			childPrefix = "  "
+		}
+
+		var line string
+		if n.Error != "" {
+			line = fmt.Sprintf("%s%s%s", folderTreeText, strings.Repeat("\t", folderTabCount),
+				colours.ColouredString("error: "+n.Error, colours.Red))
+		} else if n.IsGitRepo {
+			var sb strings.Builder
+			sb.WriteString(folderTreeText)
+			sb.WriteString("\t")
+			for _, col := range cols {
+				sb.WriteString(col.Cell(n, now))
+				sb.WriteString("\t")
+			}
+			line = sb.String()
+			if gitOpts.ShouldUpdate() {
+				line += git.PrettyUpdateResult(n.UpdateResult)
+			}
 		} else {
 			line = fmt.Sprintf("%s%s", folderTreeText, strings.Repeat("\t", folderTabCount))
 		}
 		fmt.Fprintln(w, line)
 
+		for _, v := range n.Violations {
+			fmt.Fprintf(w, "%s%s%s [%s] %s\n", childPrefix, sym.Violation, colouredSeverity(v.Severity), v.Rule, v.Message)
+		}
+
 		// check if we want to print files as well
 		if gitOpts.ShowFiles {
 			if len(n.GitStats.ChangedFiles) > 0 {
 				for _, line := range n.GitStats.ChangedFiles {
-					fileLine := fmt.Sprintf("%s   |-- %s", leftPad, line)
+					fileLine := childPrefix + sym.File + line
 					fmt.Fprintln(w, fileLine)
 				}
 			}
 		}
 	})
 }
+
+// treePrefixes returns what's drawn before a node's name, and before the
+// lines beneath it
+func treePrefixes(n *t.Node, sym symbols.Set) (string, string) {
+	if n.Parent == nil {
+		return sym.Root, sym.RootIndent
+	}
+
+	_, parentPrefix := treePrefixes(n.Parent, sym)
+	siblings := n.Parent.Children
+	if siblings[len(siblings)-1] == n {
+		return parentPrefix + sym.Last, parentPrefix + sym.Blank
+	}
+	return parentPrefix + sym.Branch, parentPrefix + sym.Vertical
+}
@@ -0,0 +1,146 @@
+package rgst
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jobodd/rgst/internal/colours"
+	"github.com/jobodd/rgst/internal/columns"
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/symbols"
+	"github.com/jobodd/rgst/internal/table"
+	"github.com/jobodd/rgst/internal/tree"
+)
+
+// drawTree is dev/{api, libs/{core, util}, web}, so libs is a subtree
+// with a sibling after it
+func drawTree() *tree.Node {
+	root := tree.NewNode("dev", "/dev", nil)
+	add := func(parent *tree.Node, name string, isRepo bool, files ...string) *tree.Node {
+		n := tree.NewNode(name, parent.AbsPath+"/"+name, parent)
+		n.IsGitRepo = isRepo
+		n.GitStats.ChangedFiles = files
+		parent.Children = append(parent.Children, n)
+		return n
+	}
+	add(root, "api", true, "[??] new.txt")
+	libs := add(root, "libs", false)
+	add(libs, "core", true, "[??] f")
+	add(libs, "util", true)
+	add(root, "web", true)
+	return root
+}
+
+// renderTree returns printDirTree's lines with the column padding trimmed
+func renderTree(t *testing.T, root *tree.Node, sym symbols.Set, gitOpts git.GitOptions, cols []columns.Column, flat bool) []string {
+	current, enabled := symbols.Current, colours.Enabled()
+	symbols.Current = sym
+	colours.SetEnabled(false)
+	t.Cleanup(func() {
+		symbols.Current = current
+		colours.SetEnabled(enabled)
+	})
+
+	var sb strings.Builder
+	w := table.NewWriter(&sb, 1)
+	printDirTree(w, root, gitOpts, cols, flat)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return lines
+}
+
+func TestPrintDirTree_Unicode(t *testing.T) {
+	got := renderTree(t, drawTree(), symbols.Unicode, git.GitOptions{ShowFiles: true}, nil, false)
+	want := []string{
+		"dev",
+		"├── api",
+		"│   · [??] new.txt",
+		"├── libs",
+		"│   ├── core",
+		"│   │   · [??] f",
+		"│   └── util",
+		"└── web",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Failed test: Got: %q, Want: %q", got, want)
+	}
+}
+
+// the ASCII set draws the tree exactly as rgst did before symbol sets
+func TestPrintDirTree_ASCIIBaseline(t *testing.T) {
+	root := drawTree()
+	got := renderTree(t, root, symbols.ASCII, git.GitOptions{ShowFiles: true}, nil, false)
+
+	var want []string
+	tree.Walk(root, func(n *tree.Node) {
+		leftPad := strings.Repeat("  ", n.GetDepth())
+		want = append(want, leftPad+"|-- "+n.FolderName)
+		for _, file := range n.GitStats.ChangedFiles {
+			want = append(want, leftPad+"   |-- "+file)
+		}
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Failed test: Got: %q, Want: %q", got, want)
+	}
+}
+
+// flatTree gives drawTree's repos branches, a violation and an unreadable
+// directory
+func flatTree() *tree.Node {
+	root := drawTree()
+	api, libs, web := root.Children[0], root.Children[1], root.Children[2]
+	core := libs.Children[0]
+	api.GitStats.CurrentBranch = "main"
+	core.GitStats.CurrentBranch = "feature/x"
+	libs.Children[1].GitStats.CurrentBranch = "main"
+	web.GitStats.CurrentBranch = "dev"
+	core.Violations = []tree.Violation{{Rule: "behind", Severity: "error", Message: "2 commits behind"}}
+	locked := tree.NewNode("locked", "/dev/libs/locked", libs)
+	locked.Error = "can't read directory: permission denied"
+	libs.Children = append(libs.Children, locked)
+	return root
+}
+
+func TestPrintDirTree_Flat(t *testing.T) {
+	root := flatTree()
+	root.Children[1].Children[0].Violations = nil
+
+	// the plain libs directory is dropped, the unreadable one kept
+	cols, _ := columns.Parse("branch")
+	got := renderTree(t, root, symbols.Unicode, git.GitOptions{}, cols, true)
+	want := []string{
+		"api         main",
+		"libs/core   feature/x",
+		"libs/util   main",
+		"libs/locked           error: can't read directory: permission denied",
+		"web         dev",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Failed test: Got: %q, Want: %q", got, want)
+	}
+}
+
+// files and violations sit two spaces in under their repo, whatever its
+// depth
+func TestPrintDirTree_FlatIndent(t *testing.T) {
+	got := renderTree(t, flatTree(), symbols.Unicode, git.GitOptions{ShowFiles: true}, nil, true)
+	want := []string{
+		"api",
+		"  · [??] new.txt",
+		"libs/core",
+		"  ! error: [behind] 2 commits behind",
+		"  · [??] f",
+		"libs/util",
+		"libs/locked error: can't read directory: permission denied",
+		"web",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Failed test: Got: %q, Want: %q", got, want)
+	}
+}
@@ -0,0 +1,44 @@
+package rgst
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jobodd/rgst/internal/server"
+)
+
+const DefaultListenAddr = "127.0.0.1:7420"
+
+func Serve(opts Options) error {
+	// see watchRepos
+	os.Setenv("GIT_OPTIONAL_LOCKS", "0")
+
+	if !opts.NoCache {
+		statsCache = loadCache()
+		defer saveCache()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ws := &workspace{opts: opts}
+	ws.scan()
+	go ws.watch(ctx)
+
+	srv := &http.Server{Addr: opts.ListenAddr, Handler: server.NewHandler(ws)}
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
+	fmt.Fprintf(os.Stderr, "rgst dashboard on http://%s\n", opts.ListenAddr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build !unix
+
+package rgst
+
+import (
+	"net"
+	"os"
+)
+
+func checkSocketDir(dir string) error {
+	return nil
+}
+
+func listenUnix(socketPath string) (net.Listener, error) {
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
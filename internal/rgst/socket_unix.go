@@ -0,0 +1,32 @@
+//go:build unix
+
+package rgst
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// checkSocketDir refuses a directory where someone else could swap the
+// socket for their own
+func checkSocketDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !info.IsDir() || !ok || int(stat.Uid) != os.Getuid() || info.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("Won't listen in %s, which must be a directory owned by you that only you can write to", dir)
+	}
+	return nil
+}
+
+// listenUnix creates the socket closed to other users from the start,
+// rather than tightening it once they could already have connected
+func listenUnix(socketPath string) (net.Listener, error) {
+	old := syscall.Umask(0177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", socketPath)
+}
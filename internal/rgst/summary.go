@@ -0,0 +1,120 @@
+package rgst
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jobodd/rgst/internal/colours"
+	"github.com/jobodd/rgst/internal/plural"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+type phase struct {
+	name string
+	took time.Duration
+}
+
+type totals struct {
+	repos      int
+	clean      int
+	dirty      int
+	ahead      int
+	behind     int
+	noRemote   int
+	detached   int
+	withErrors int
+	unreadable int
+}
+
+func countTotals(root *t.Node) totals {
+	var sum totals
+	t.Walk(root, func(n *t.Node) {
+		if !n.IsGitRepo {
+			if n.Error != "" {
+				sum.unreadable++
+			}
+			return
+		}
+		sum.repos++
+		if n.Error != "" || n.UpdateResult.Failed() {
+			sum.withErrors++
+		}
+		if n.Error != "" {
+			return
+		}
+
+		if len(n.GitStats.ChangedFiles) > 0 {
+			sum.dirty++
+		} else {
+			sum.clean++
+		}
+		// -1 means there's no upstream to compare against
+		sum.ahead += max(n.GitStats.CommitsAheadOfRemote, 0)
+		sum.behind += max(n.GitStats.CommitsBehindRemote, 0)
+		if n.GitStats.RemotesCount == 0 {
+			sum.noRemote++
+		}
+		if n.GitStats.CurrentBranch == "HEAD" {
+			sum.detached++
+		}
+	})
+	return sum
+}
+
+// printSummary writes the footer shown with --summary. Phases are left
+// out when redrawing in watch mode, where they'd only describe the
+// first scan.
+func printSummary(out io.Writer, root *t.Node, phases []phase) {
+	sum := countTotals(root)
+
+	fmt.Fprintf(out, "\n%s: %d clean, %s\n",
+		plural.Count(sum.repos, "repo"),
+		sum.clean,
+		colourIfAny(sum.dirty, "dirty", colours.Yellow),
+	)
+	fmt.Fprintf(out, "Commits: %d ahead, %d behind\n", sum.ahead, sum.behind)
+	fmt.Fprintf(out, "%d without remotes, %d on a detached HEAD, %s\n",
+		sum.noRemote,
+		sum.detached,
+		colourIfAny(sum.withErrors, "with errors", colours.Red),
+	)
+	printUnreadable(out, sum.unreadable)
+
+	if len(phases) > 0 {
+		var total time.Duration
+		var parts []string
+		for _, p := range phases {
+			total += p.took
+			parts = append(parts, fmt.Sprintf("%s %s", p.name, roundDuration(p.took)))
+		}
+		fmt.Fprintf(out, "Took %s: %s\n", roundDuration(total), strings.Join(parts, ", "))
+	}
+}
+
+func colourIfAny(count int, label string, colour string) string {
+	s := fmt.Sprintf("%d %s", count, label)
+	if count == 0 {
+		return s
+	}
+	return colours.ColouredString(s, colour)
+}
+
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(10 * time.Millisecond)
+}
+
+// printUnreadable counts the directories that couldn't be searched, which
+// aren't repos but may hold some
+func printUnreadable(out io.Writer, unreadable int) {
+	if unreadable > 0 {
+		fmt.Fprintln(out, colours.ColouredString(plural.Count(unreadable, "directory")+" couldn't be read", colours.Red))
+	}
+}
@@ -0,0 +1,91 @@
+package rgst
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jobodd/rgst/internal/columns"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+// templateRepo is what a --template is executed against, once per repo.
+// Counts are -1 where there's nothing to compare against.
+type templateRepo struct {
+	Path       string
+	AbsPath    string
+	Branch     string
+	Ahead      int
+	Behind     int
+	Added      int
+	Removed    int
+	Modified   int
+	Unstaged   int
+	Untracked  int
+	Dirty      int
+	Stashes    int
+	Tag        string
+	Remotes    []string
+	Files      []string
+	LastCommit time.Time
+	Age        string
+	Update     string
+	Error      string
+	Violations []t.Violation
+}
+
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("rgst").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Bad --template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func printTemplate(out io.Writer, root *t.Node, tmpl *template.Template) error {
+	now := time.Now()
+	var err error
+	t.Walk(root, func(n *t.Node) {
+		if !n.IsGitRepo || err != nil {
+			return
+		}
+		var sb strings.Builder
+		if err = tmpl.Execute(&sb, newTemplateRepo(n, now)); err != nil {
+			return
+		}
+		// one repo per line, whether or not the template ends in a newline
+		_, err = fmt.Fprintln(out, strings.TrimSuffix(sb.String(), "\n"))
+	})
+	return err
+}
+
+func newTemplateRepo(n *t.Node, now time.Time) templateRepo {
+	g := n.GitStats
+	repo := templateRepo{
+		Path:       n.RelPath(),
+		AbsPath:    n.AbsPath,
+		Branch:     g.CurrentBranch,
+		Ahead:      g.CommitsAheadOfRemote,
+		Behind:     g.CommitsBehindRemote,
+		Added:      g.FilesAddedCount,
+		Removed:    g.FilesRemovedCount,
+		Modified:   g.FilesModifiedCount,
+		Unstaged:   g.FilesUnstagedCount,
+		Untracked:  g.UntrackedCount,
+		Dirty:      g.DirtyCount(),
+		Stashes:    g.StashCount,
+		Tag:        g.HeadTag,
+		Remotes:    g.RemoteURLs,
+		Files:      g.ChangedFiles,
+		Update:     n.UpdateResult.String(),
+		Error:      n.Error,
+		Violations: n.Violations,
+	}
+	if g.LastCommitTime != 0 {
+		repo.LastCommit = time.Unix(g.LastCommitTime, 0)
+		repo.Age = columns.Age(repo.LastCommit, now)
+	}
+	return repo
+}
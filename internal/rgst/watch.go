@@ -0,0 +1,62 @@
+package rgst
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	t "github.com/jobodd/rgst/internal/tree"
+	"github.com/jobodd/rgst/internal/watch"
+)
+
+const (
+	clearScreen = "\033[H\033[2J"
+)
+
+func watchRepos(root *t.Node, opts Options) error {
+	// stop `git status` from refreshing the index, which would wake the
+	// watcher up again every time we redraw
+	os.Setenv("GIT_OPTIONAL_LOCKS", "0")
+
+	reposByPath := map[string]*t.Node{}
+	var paths []string
+	t.Walk(root, func(n *t.Node) {
+		if n.IsGitRepo {
+			reposByPath[n.AbsPath] = n
+			paths = append(paths, n.AbsPath)
+		}
+	})
+
+	w := watch.New(paths, opts.PollInterval)
+	defer w.Close()
+
+	redraw := func() error {
+		fmt.Print(clearScreen)
+		if err := printOutput(os.Stdout, root, opts); err != nil {
+			return err
+		}
+		if opts.Summary {
+			printSummary(os.Stdout, root, nil)
+		}
+		fmt.Printf("\nWatching %d repos (%s). Last updated %s. Press Ctrl-C to quit.\n",
+			len(paths), w.Backend, time.Now().Format("15:04:05"))
+		return nil
+	}
+
+	if err := redraw(); err != nil {
+		return err
+	}
+	for changed := range w.Events {
+		for _, path := range changed {
+			n := reposByPath[path]
+			gitStats, err := gitStatsFor(n, opts)
+			setGitStats(n, gitStats, err)
+		}
+		if err := redraw(); err != nil {
+			return err
+		}
+		// watch mode only ends with Ctrl-C, so save as we go
+		saveCache()
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package rgst
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jobodd/rgst/internal/git"
+	t "github.com/jobodd/rgst/internal/tree"
+	"github.com/jobodd/rgst/internal/watch"
+)
+
+// new or removed repos aren't seen by the watcher, so look for them
+// every so often
+const rescanInterval = time.Minute
+
+// workspace keeps a tree and its stats up to date for long running modes
+// like the daemon and the dashboard
+type workspace struct {
+	opts Options
+	mu   sync.RWMutex
+	root *t.Node
+}
+
+func (ws *workspace) View(visit func(root *t.Node)) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	visit(ws.root)
+}
+
+func (ws *workspace) Refresh(absPath string) error {
+	n, err := ws.repo(absPath)
+	if err != nil {
+		return err
+	}
+	ws.refresh(n)
+	return nil
+}
+
+func (ws *workspace) Fetch(absPath string) error {
+	n, err := ws.repo(absPath)
+	if err != nil {
+		return err
+	}
+	result := git.UpdateDirectory(n.AbsPath, git.GitOptions{ShouldFetch: true})
+	ws.mu.Lock()
+	n.UpdateResult = result
+	ws.mu.Unlock()
+	ws.refresh(n)
+	return nil
+}
+
+func (ws *workspace) repo(absPath string) (*t.Node, error) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	var found *t.Node
+	t.Walk(ws.root, func(n *t.Node) {
+		if n.IsGitRepo && n.AbsPath == absPath {
+			found = n
+		}
+	})
+	if found == nil {
+		return nil, fmt.Errorf("%s isn't a known repository", absPath)
+	}
+	return found, nil
+}
+
+func (ws *workspace) scan() {
+	root := discoverRepos(ws.opts)
+	if root != nil {
+		collectGitStats(root, ws.opts, nil)
+	}
+
+	ws.mu.Lock()
+	ws.root = root
+	ws.mu.Unlock()
+}
+
+func (ws *workspace) watch(ctx context.Context) {
+	for {
+		reposByPath := map[string]*t.Node{}
+		var paths []string
+		ws.mu.RLock()
+		t.Walk(ws.root, func(n *t.Node) {
+			if n.IsGitRepo {
+				reposByPath[n.AbsPath] = n
+				paths = append(paths, n.AbsPath)
+			}
+		})
+		ws.mu.RUnlock()
+
+		w := watch.New(paths, ws.opts.PollInterval)
+		rescan := time.NewTimer(rescanInterval)
+
+	events:
+		for {
+			select {
+			case changed := <-w.Events:
+				for _, path := range changed {
+					ws.refresh(reposByPath[path])
+				}
+				saveCache()
+			case <-rescan.C:
+				break events
+			case <-ctx.Done():
+				w.Close()
+				return
+			}
+		}
+
+		w.Close()
+		ws.scan()
+		saveCache()
+	}
+}
+
+func (ws *workspace) refresh(n *t.Node) {
+	gitStats, err := gitStatsFor(n, ws.opts)
+	ws.mu.Lock()
+	setGitStats(n, gitStats, err)
+	ws.mu.Unlock()
+}
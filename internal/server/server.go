@@ -0,0 +1,125 @@
+package server
+
+import (
+	"embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/metrics"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+//go:embed templates/dashboard.html
+var templates embed.FS
+
+var dashboard = template.Must(template.New("dashboard.html").Funcs(template.FuncMap{
+	"indent": func(depth int) float64 { return float64(depth) * 1.5 },
+}).ParseFS(templates, "templates/dashboard.html"))
+
+// Workspace is the tree being served. View must not hold on to the tree
+// after it returns, as Refresh and Fetch update it in place.
+type Workspace interface {
+	View(visit func(root *t.Node))
+	Refresh(absPath string) error
+	Fetch(absPath string) error
+}
+
+type row struct {
+	Depth        int
+	Name         string
+	AbsPath      string
+	IsGitRepo    bool
+	GitStats     git.GitStats
+	UpdateResult git.UpdateResult
+}
+
+func NewHandler(ws Workspace) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		var rows []row
+		var rootPath string
+		ws.View(func(root *t.Node) {
+			if root != nil {
+				rootPath = root.AbsPath
+			}
+			t.Walk(root, func(n *t.Node) {
+				rows = append(rows, row{
+					Depth:        n.GetDepth(),
+					Name:         n.FolderName,
+					AbsPath:      n.AbsPath,
+					IsGitRepo:    n.IsGitRepo,
+					GitStats:     n.GitStats,
+					UpdateResult: n.UpdateResult,
+				})
+			})
+		})
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := dashboard.Execute(w, map[string]any{"Root": rootPath, "Rows": rows}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	mux.HandleFunc("GET /api/tree", func(w http.ResponseWriter, r *http.Request) {
+		ws.View(func(root *t.Node) {
+			writeJSON(w, root)
+		})
+	})
+
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		ws.View(func(root *t.Node) {
+			metrics.WritePrometheus(w, root, time.Now())
+		})
+	})
+
+	mux.HandleFunc("POST /api/repos/refresh", repoAction(ws, ws.Refresh))
+	mux.HandleFunc("POST /api/repos/fetch", repoAction(ws, ws.Fetch))
+
+	return mux
+}
+
+func repoAction(ws Workspace, action func(absPath string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !sameOrigin(r) {
+			http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+
+		absPath := r.URL.Query().Get("path")
+		if err := action(absPath); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		ws.View(func(root *t.Node) {
+			t.Walk(root, func(n *t.Node) {
+				if n.AbsPath == absPath {
+					writeJSON(w, n)
+				}
+			})
+		})
+	}
+}
+
+// stops other sites open in the browser from triggering fetches
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return r.Header.Get("Sec-Fetch-Site") != "cross-site"
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/tree"
+)
+
+type fakeWorkspace struct {
+	root    *tree.Node
+	fetched []string
+}
+
+func (f *fakeWorkspace) View(visit func(root *tree.Node)) {
+	visit(f.root)
+}
+
+func (f *fakeWorkspace) Refresh(absPath string) error {
+	return f.find(absPath)
+}
+
+func (f *fakeWorkspace) Fetch(absPath string) error {
+	if err := f.find(absPath); err != nil {
+		return err
+	}
+	f.fetched = append(f.fetched, absPath)
+	return nil
+}
+
+func (f *fakeWorkspace) find(absPath string) error {
+	found := false
+	tree.Walk(f.root, func(n *tree.Node) {
+		found = found || (n.IsGitRepo && n.AbsPath == absPath)
+	})
+	if !found {
+		return errors.New("unknown repo")
+	}
+	return nil
+}
+
+func newFakeWorkspace() *fakeWorkspace {
+	root := tree.NewNode("dev", "/home/me/dev", nil)
+	repo := tree.NewNode("rgst", "/home/me/dev/rgst", root)
+	repo.IsGitRepo = true
+	repo.GitStats = git.GitStats{CurrentBranch: "develop", CommitsBehindRemote: 3}
+	root.Children = append(root.Children, repo)
+	return &fakeWorkspace{root: root}
+}
+
+func TestDashboard(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHandler(newFakeWorkspace()).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf(`Failed test: Got status: %v, Want: %v`, rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"rgst", "develop", `class="bad">3<`, `data-path="/home/me/dev/rgst"`} {
+		if !strings.Contains(body, want) {
+			t.Fatalf(`Failed test: Want %q in body: %s`, want, body)
+		}
+	}
+}
+
+func TestAPITree(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHandler(newFakeWorkspace()).ServeHTTP(rec, httptest.NewRequest("GET", "/api/tree", nil))
+
+	var root tree.Node
+	if err := json.Unmarshal(rec.Body.Bytes(), &root); err != nil {
+		t.Fatalf("Failed test with error: %s", err)
+	}
+	got := root.Children[0].GitStats.CurrentBranch
+	want := "develop"
+	if got != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHandler(newFakeWorkspace()).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	want := `rgst_repo_commits_behind{path="/home/me/dev/rgst",branch="develop"} 3`
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Fatalf(`Failed test: Want %q in body: %s`, want, rec.Body.String())
+	}
+}
+
+func TestAPIFetch(t *testing.T) {
+	ws := newFakeWorkspace()
+	rec := httptest.NewRecorder()
+	NewHandler(ws).ServeHTTP(rec, httptest.NewRequest("POST", "/api/repos/fetch?path=/home/me/dev/rgst", nil))
+
+	if rec.Code != http.StatusOK || len(ws.fetched) != 1 {
+		t.Fatalf(`Failed test: Got status: %v, fetched: %v`, rec.Code, ws.fetched)
+	}
+
+	rec = httptest.NewRecorder()
+	NewHandler(ws).ServeHTTP(rec, httptest.NewRequest("POST", "/api/repos/fetch?path=/etc", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf(`Failed test: Got status: %v, Want: %v`, rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestAPIFetch_CrossOrigin(t *testing.T) {
+	ws := newFakeWorkspace()
+	req := httptest.NewRequest("POST", "/api/repos/fetch?path=/home/me/dev/rgst", nil)
+	req.Header.Set("Origin", "https://attacker.test")
+	rec := httptest.NewRecorder()
+	NewHandler(ws).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden || len(ws.fetched) != 0 {
+		t.Fatalf(`Failed test: Got status: %v, fetched: %v`, rec.Code, ws.fetched)
+	}
+}
+
+func TestAPIFetch_MethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHandler(newFakeWorkspace()).ServeHTTP(rec, httptest.NewRequest("GET", "/api/repos/fetch", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf(`Failed test: Got status: %v, Want: %v`, rec.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,230 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/plural"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+const version = 1
+
+type Snapshot struct {
+	Version int            `json:"version"`
+	TakenAt time.Time      `json:"takenAt"`
+	Root    string         `json:"root"`
+	Depth   uint           `json:"depth"`
+	Filters t.SavedFilters `json:"filters"`
+	Repos   []Repo         `json:"repos"`
+}
+
+type Repo struct {
+	Path     string       `json:"path"`
+	AbsPath  string       `json:"absPath"`
+	Head     string       `json:"head"`
+	GitStats git.GitStats `json:"gitStats"`
+	Error    string       `json:"error,omitempty"`
+}
+
+const (
+	KindAppeared    = "appeared"
+	KindDisappeared = "disappeared"
+	KindChanged     = "changed"
+)
+
+type Change struct {
+	Path    string   `json:"path"`
+	Kind    string   `json:"kind"`
+	Details []string `json:"details,omitempty"`
+}
+
+// CommitCounter counts the commits reachable from to but not from, as
+// git.CountCommits does
+type CommitCounter func(absPath string, from string, to string) (int, error)
+
+// Take records every repo under root. head looks up the commit each repo
+// is on, which GitStats doesn't keep. A repo git couldn't read is kept
+// with its error, so a passing failure isn't seen as the repo leaving. A
+// nil root, where discovery found no repos, makes an empty snapshot.
+func Take(root *t.Node, depth uint, now time.Time, head func(absPath string) string) Snapshot {
+	s := Snapshot{
+		Version: version,
+		TakenAt: now,
+		Depth:   depth,
+		Repos:   []Repo{},
+	}
+	if root == nil {
+		return s
+	}
+	s.Root = root.AbsPath
+	t.Walk(root, func(n *t.Node) {
+		if n.IsGitRepo {
+			s.Repos = append(s.Repos, Repo{
+				Path:     n.RelPath(),
+				AbsPath:  n.AbsPath,
+				Head:     head(n.AbsPath),
+				GitStats: n.GitStats,
+				Error:    n.Error,
+			})
+		}
+	})
+	return s
+}
+
+func Load(path string) (Snapshot, error) {
+	var s Snapshot
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(content, &s); err != nil {
+		return s, fmt.Errorf("%s: %w", path, err)
+	}
+	if s.Version != version {
+		return s, fmt.Errorf("%s: unsupported snapshot version %d", path, s.Version)
+	}
+	return s, nil
+}
+
+// Save writes the snapshot atomically, so an interrupted run never
+// leaves half a file behind
+func (s Snapshot) Save(path string) error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".snapshot-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(content, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Diff lists what changed between two snapshots, in the order of to's
+// repos with disappeared repos at the end. Unchanged repos are left out.
+func Diff(from Snapshot, to Snapshot, countCommits CommitCounter) []Change {
+	oldRepos := map[string]Repo{}
+	for _, r := range from.Repos {
+		oldRepos[r.Path] = r
+	}
+
+	changes := []Change{}
+	seen := map[string]bool{}
+	for _, r := range to.Repos {
+		seen[r.Path] = true
+		before, ok := oldRepos[r.Path]
+		if !ok {
+			changes = append(changes, Change{Path: r.Path, Kind: KindAppeared})
+			continue
+		}
+		if details := compare(before, r, countCommits); len(details) > 0 {
+			changes = append(changes, Change{Path: r.Path, Kind: KindChanged, Details: details})
+		}
+	}
+	for _, r := range from.Repos {
+		if !seen[r.Path] {
+			changes = append(changes, Change{Path: r.Path, Kind: KindDisappeared})
+		}
+	}
+	return changes
+}
+
+func compare(before Repo, after Repo, countCommits CommitCounter) []string {
+	// an errored repo's stats are empty, so there's nothing else to compare
+	switch {
+	case after.Error != "" && after.Error != before.Error:
+		return []string{"error: " + after.Error}
+	case after.Error != "":
+		return nil
+	case before.Error != "":
+		return []string{"error cleared"}
+	}
+
+	var details []string
+	b, a := before.GitStats, after.GitStats
+
+	if b.CurrentBranch != a.CurrentBranch {
+		details = append(details, fmt.Sprintf("branch %s -> %s", b.CurrentBranch, a.CurrentBranch))
+	}
+	if before.Head != after.Head {
+		details = append(details, describeHeadMove(after.AbsPath, before.Head, after.Head, countCommits))
+	}
+
+	bDirty, aDirty := len(b.ChangedFiles), len(a.ChangedFiles)
+	switch {
+	case bDirty == 0 && aDirty > 0:
+		details = append(details, fmt.Sprintf("became dirty (%s)", plural.Count(aDirty, "file")))
+	case bDirty > 0 && aDirty == 0:
+		details = append(details, "became clean")
+	case bDirty != aDirty:
+		details = append(details, fmt.Sprintf("changed files %d -> %d", bDirty, aDirty))
+	}
+
+	if b.CommitsAheadOfRemote != a.CommitsAheadOfRemote {
+		details = append(details, fmt.Sprintf("ahead %s -> %s", count(b.CommitsAheadOfRemote), count(a.CommitsAheadOfRemote)))
+	}
+	if b.CommitsBehindRemote != a.CommitsBehindRemote {
+		details = append(details, fmt.Sprintf("behind %s -> %s", count(b.CommitsBehindRemote), count(a.CommitsBehindRemote)))
+	}
+	if b.StashCount != a.StashCount {
+		details = append(details, fmt.Sprintf("stashes %d -> %d", b.StashCount, a.StashCount))
+	}
+	if b.HeadTag != a.HeadTag {
+		details = append(details, fmt.Sprintf("tag %s -> %s", orNone(b.HeadTag), orNone(a.HeadTag)))
+	}
+	return details
+}
+
+func describeHeadMove(absPath string, from string, to string, countCommits CommitCounter) string {
+	if from == "" || to == "" {
+		return fmt.Sprintf("HEAD %s -> %s", orNone(short(from)), orNone(short(to)))
+	}
+
+	added, errAdded := countCommits(absPath, from, to)
+	dropped, errDropped := countCommits(absPath, to, from)
+	switch {
+	case errAdded != nil || errDropped != nil:
+		return fmt.Sprintf("HEAD %s -> %s", short(from), short(to))
+	case dropped == 0:
+		return plural.Count(added, "new commit")
+	case added == 0:
+		return fmt.Sprintf("HEAD moved back %s", plural.Count(dropped, "commit"))
+	default:
+		return fmt.Sprintf("%s, %d no longer on HEAD", plural.Count(added, "new commit"), dropped)
+	}
+}
+
+func short(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// -1 is used for "no upstream"
+func count(i int) string {
+	if i < 0 {
+		return "-"
+	}
+	return fmt.Sprint(i)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+package snapshot
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/tree"
+)
+
+func TestDiff(t *testing.T) {
+	from := Snapshot{Repos: []Repo{
+		{Path: "api", Head: "aaa", GitStats: git.GitStats{CurrentBranch: "main", ChangedFiles: []string{}}},
+		{Path: "web", Head: "bbb", GitStats: git.GitStats{CurrentBranch: "main", ChangedFiles: []string{"[ M] x"}}},
+		{Path: "old", Head: "ccc"},
+		{Path: "same", Head: "ddd", GitStats: git.GitStats{CurrentBranch: "main"}},
+	}}
+	to := Snapshot{Repos: []Repo{
+		{Path: "api", Head: "aab", GitStats: git.GitStats{CurrentBranch: "feature", ChangedFiles: []string{"[??] y", "[ M] z"}}},
+		{Path: "new", Head: "eee"},
+		{Path: "same", Head: "ddd", GitStats: git.GitStats{CurrentBranch: "main"}},
+		{Path: "web", Head: "bbc", GitStats: git.GitStats{CurrentBranch: "main", ChangedFiles: []string{}}},
+	}}
+
+	// aaa..aab is 3 commits ahead, bbb and bbc have diverged
+	counts := map[string]int{"aaa..aab": 3, "aab..aaa": 0, "bbb..bbc": 1, "bbc..bbb": 2}
+	countCommits := func(absPath string, from string, to string) (int, error) {
+		n, ok := counts[from+".."+to]
+		if !ok {
+			return 0, errors.New("unknown revision")
+		}
+		return n, nil
+	}
+
+	got := Diff(from, to, countCommits)
+	want := []Change{
+		{Path: "api", Kind: KindChanged, Details: []string{"branch main -> feature", "3 new commits", "became dirty (2 files)"}},
+		{Path: "new", Kind: KindAppeared},
+		{Path: "web", Kind: KindChanged, Details: []string{"1 new commit, 2 no longer on HEAD", "became clean"}},
+		{Path: "old", Kind: KindDisappeared},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	s := Snapshot{
+		Version: version,
+		TakenAt: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+		Root:    "/dev",
+		Depth:   2,
+		Filters: tree.SavedFilters{Include: []string{"work/**"}, FollowSymlinks: true},
+		Repos:   []Repo{{Path: "api", AbsPath: "/dev/api", Head: "aaa", GitStats: git.GitStats{CurrentBranch: "main", ChangedFiles: []string{}, RemoteURLs: []string{}}}},
+	}
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, s) {
+		t.Fatalf(`Failed test: Got: %+v, Want: %+v`, got, s)
+	}
+}
+
+func TestTake_NoRepos(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	from := Snapshot{Repos: []Repo{{Path: "api", Head: "aaa"}}}
+	to := Take(nil, 1, now, func(absPath string) string { return "" })
+	if len(to.Repos) != 0 {
+		t.Fatalf(`Failed test: Got: %v, Want: no repos`, to.Repos)
+	}
+
+	got := Diff(from, to, nil)
+	want := []Change{{Path: "api", Kind: KindDisappeared}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
+
+func TestTake_KeepsErroredRepos(t *testing.T) {
+	root := tree.NewNode("dev", "/dev", nil)
+	for _, name := range []string{"api", "web"} {
+		n := tree.NewNode(name, "/dev/"+name, root)
+		n.IsGitRepo = true
+		root.Children = append(root.Children, n)
+	}
+	root.Children[1].Error = "index.lock: File exists"
+	head := func(absPath string) string { return "aaa" }
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	s := Take(root, 1, now, head)
+	want := []Repo{
+		{Path: "api", AbsPath: "/dev/api", Head: "aaa"},
+		{Path: "web", AbsPath: "/dev/web", Head: "aaa", Error: "index.lock: File exists"},
+	}
+	if !reflect.DeepEqual(s.Repos, want) {
+		t.Fatalf(`Failed test: Got: %+v, Want: %+v`, s.Repos, want)
+	}
+}
+
+func TestDiff_ErroredRepos(t *testing.T) {
+	from := Snapshot{Repos: []Repo{
+		{Path: "api", Head: "aaa", GitStats: git.GitStats{CurrentBranch: "main"}},
+		{Path: "web", Head: "bbb", Error: "index.lock: File exists"},
+		{Path: "db", Head: "ccc", Error: "index.lock: File exists"},
+	}}
+	to := Snapshot{Repos: []Repo{
+		{Path: "api", Head: "aaa", Error: "bad object HEAD"},
+		{Path: "web", Head: "bbb", GitStats: git.GitStats{CurrentBranch: "main"}},
+		{Path: "db", Head: "ccc", Error: "index.lock: File exists"},
+	}}
+
+	got := Diff(from, to, nil)
+	want := []Change{
+		{Path: "api", Kind: KindChanged, Details: []string{"error: bad object HEAD"}},
+		{Path: "web", Kind: KindChanged, Details: []string{"error cleared"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+	}
+}
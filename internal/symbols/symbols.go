@@ -0,0 +1,152 @@
+package symbols
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Set is the characters used to draw the tree and the stats columns.
+// A line is drawn as its ancestors' Vertical or Blank continuations,
+// then Branch (or Last for a parent's last child), then the name.
+type Set struct {
+	Name string
+
+	Root       string
+	RootIndent string
+	Branch     string
+	Last       string
+	Vertical   string
+	Blank      string
+	File       string
+	Violation  string
+	Dir        string
+	Repo       string
+
+	Ahead        string
+	Behind       string
+	AheadBranch  string
+	BehindBranch string
+	Added        string
+	Removed      string
+	Modified     string
+	Unstaged     string
+	Untracked    string
+	Dirty        string
+	Stashes      string
+}
+
+const Auto = "auto"
+
+// ASCII is safe for legacy consoles and log files, and draws the tree
+// the way rgst always has
+var ASCII = Set{
+	Name:       "ascii",
+	Root:       "|-- ",
+	RootIndent: "  ",
+	Branch:     "|-- ",
+	Last:       "|-- ",
+	Vertical:   "  ",
+	Blank:      "  ",
+	File:       " |-- ",
+	Violation:  " ! ",
+
+	Ahead:        "^",
+	Behind:       "v",
+	AheadBranch:  ">",
+	BehindBranch: "< ",
+	Added:        "+",
+	Removed:      "-",
+	Modified:     "~",
+	Unstaged:     "U",
+	Untracked:    "?",
+	Dirty:        "*",
+	Stashes:      "$",
+}
+
+var Unicode = Set{
+	Name:      "unicode",
+	Branch:    "├── ",
+	Last:      "└── ",
+	Vertical:  "│   ",
+	Blank:     "    ",
+	File:      "· ",
+	Violation: "! ",
+
+	Ahead:        "↑",
+	Behind:       "↓",
+	AheadBranch:  "→",
+	BehindBranch: "← ",
+	Added:        "+",
+	Removed:      "-",
+	Modified:     "~",
+	Unstaged:     "U",
+	Untracked:    "?",
+	Dirty:        "*",
+	Stashes:      "$",
+}
+
+// Nerd needs a patched font from https://www.nerdfonts.com
+var Nerd = Set{
+	Name:      "nerd",
+	Branch:    "├── ",
+	Last:      "└── ",
+	Vertical:  "│   ",
+	Blank:     "    ",
+	File:      "\uf15b ",
+	Violation: "\uf071 ",
+	Dir:       "\uf07b ",
+	Repo:      "\ue702 ",
+
+	Ahead:        "\uf062 ",
+	Behind:       "\uf063 ",
+	AheadBranch:  "\uf061 ",
+	BehindBranch: "\uf060 ",
+	Added:        "\uf457 ",
+	Removed:      "\uf458 ",
+	Modified:     "\uf459 ",
+	Unstaged:     "\uf044 ",
+	Untracked:    "\uf128 ",
+	Dirty:        "\uf111 ",
+	Stashes:      "\uf187 ",
+}
+
+var Sets = []Set{ASCII, Unicode, Nerd}
+
+// Current is the set output is drawn with, chosen by Use
+var Current = Unicode
+
+// Use picks a set by name. "auto" picks Unicode where the locale says the
+// terminal can show it, and ASCII otherwise; Nerd Font glyphs can't be
+// detected, so are only used when asked for.
+func Use(name string) error {
+	if name == Auto || name == "" {
+		name = Detect()
+	}
+	for _, set := range Sets {
+		if set.Name == name {
+			Current = set
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown symbol set %q. Expected one of: auto, ascii, unicode, nerd", name)
+}
+
+func Detect() string {
+	// the Linux virtual console's font has few of the glyphs
+	if os.Getenv("TERM") == "linux" {
+		return ASCII.Name
+	}
+
+	// the first of these that's set wins, as with setlocale(3)
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			locale = strings.ToLower(locale)
+			if strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8") {
+				return Unicode.Name
+			}
+			return ASCII.Name
+		}
+	}
+	return ASCII.Name
+}
@@ -0,0 +1,36 @@
+package symbols
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		term, lcAll, lang string
+		want              string
+	}{
+		{"xterm-256color", "", "en_GB.UTF-8", "unicode"},
+		{"xterm-256color", "", "de_DE.utf8", "unicode"},
+		{"xterm-256color", "C", "en_GB.UTF-8", "ascii"},
+		{"xterm-256color", "", "", "ascii"},
+		{"linux", "", "en_GB.UTF-8", "ascii"},
+	}
+	for _, c := range cases {
+		t.Setenv("TERM", c.term)
+		t.Setenv("LC_ALL", c.lcAll)
+		t.Setenv("LC_CTYPE", "")
+		t.Setenv("LANG", c.lang)
+		if got := Detect(); got != c.want {
+			t.Fatalf(`Failed test: %+v Got: %v, Want: %v`, c, got, c.want)
+		}
+	}
+}
+
+func TestUse(t *testing.T) {
+	defer func() { Current = Unicode }()
+
+	if err := Use("nerd"); err != nil || Current.Name != "nerd" {
+		t.Fatalf(`Failed test: Got: %v %v, Want: nerd`, Current.Name, err)
+	}
+	if err := Use("emoji"); err == nil {
+		t.Fatalf(`Failed test: Got: nil, Want: an error for an unknown set`)
+	}
+}
@@ -0,0 +1,97 @@
+package table
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/jobodd/rgst/internal/colours"
+)
+
+// Writer aligns tab separated cells into columns like text/tabwriter,
+// but measures cells by their visible width, so coloured and plain
+// cells line up. Like tabwriter, a cell only belongs to a column if
+// it's terminated by a tab, and a line with fewer cells ends the column
+// block above it. Output is buffered until Flush.
+type Writer struct {
+	out     io.Writer
+	padding int
+	buf     bytes.Buffer
+	lines   [][]string
+	widths  []int
+	result  bytes.Buffer
+}
+
+func NewWriter(out io.Writer, padding int) *Writer {
+	return &Writer{out: out, padding: padding}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *Writer) Flush() error {
+	text := w.buf.String()
+	w.buf.Reset()
+	if text == "" {
+		return nil
+	}
+
+	// a trailing partial line is kept as a last line without a newline
+	trailingNewline := strings.HasSuffix(text, "\n")
+	w.lines = nil
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		w.lines = append(w.lines, strings.Split(line, "\t"))
+	}
+
+	w.result.Reset()
+	w.widths = w.widths[:0]
+	w.format(0, len(w.lines))
+	if !trailingNewline {
+		w.result.Truncate(w.result.Len() - 1)
+	}
+	_, err := w.out.Write(w.result.Bytes())
+	return err
+}
+
+// format sizes the column after w.widths for each block of lines that
+// have a cell in it, recursing for the columns to its right
+func (w *Writer) format(line0 int, line1 int) {
+	column := len(w.widths)
+	for this := line0; this < line1; this++ {
+		if column >= len(w.lines[this])-1 {
+			continue
+		}
+
+		// the lines above this block don't reach this column
+		w.writeLines(line0, this)
+		line0 = this
+
+		width := 0
+		for ; this < line1; this++ {
+			line := w.lines[this]
+			if column >= len(line)-1 {
+				break
+			}
+			width = max(width, colours.VisibleWidth(line[column])+w.padding)
+		}
+
+		w.widths = append(w.widths, width)
+		w.format(line0, this)
+		w.widths = w.widths[:len(w.widths)-1]
+		line0 = this
+	}
+	w.writeLines(line0, line1)
+}
+
+func (w *Writer) writeLines(line0 int, line1 int) {
+	for _, line := range w.lines[line0:line1] {
+		for j, cell := range line {
+			w.result.WriteString(cell)
+			if j < len(w.widths) {
+				w.result.WriteString(strings.Repeat(" ", w.widths[j]-colours.VisibleWidth(cell)))
+			}
+		}
+		w.result.WriteByte('\n')
+	}
+}
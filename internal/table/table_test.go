@@ -0,0 +1,47 @@
+package table
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/tabwriter"
+)
+
+func TestWriter_MatchesTabwriter(t *testing.T) {
+	inputs := []string{
+		"|-- dev\t\t\t\t\n  |-- api\tmain\t↑0\t↓1\t\n  |-- web\tfeature/long\t↑12\t↓0\tupdated 2 refs\n",
+		"a\tb\tc\nlonger text\n  |-- file\nx\ty\tz\t\n",
+		"no tabs at all\nstill none",
+		"a\tb\n\naaa\tbbb\tccc\n",
+	}
+	for _, input := range inputs {
+		var want bytes.Buffer
+		tw := tabwriter.NewWriter(&want, 0, 0, 1, ' ', 0)
+		tw.Write([]byte(input))
+		tw.Flush()
+
+		var got bytes.Buffer
+		w := NewWriter(&got, 1)
+		w.Write([]byte(input))
+		w.Flush()
+
+		if got.String() != want.String() {
+			t.Fatalf("Failed test: Got:\n%q\nWant:\n%q", got.String(), want.String())
+		}
+	}
+}
+
+func TestWriter_IgnoresEscapes(t *testing.T) {
+	var got bytes.Buffer
+	w := NewWriter(&got, 1)
+	w.Write([]byte("api\t\033[31m↓12\033[0m\tx\nweb\t↓0\tx\n"))
+	w.Flush()
+
+	lines := strings.Split(got.String(), "\n")
+	want := []string{"api \033[31m↓12\033[0m x", "web ↓0  x"}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf(`Failed test: Got: %q, Want: %q`, lines[i], want[i])
+		}
+	}
+}
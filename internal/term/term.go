@@ -0,0 +1,27 @@
+package term
+
+import "os"
+
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func Width(f *os.File) int {
+	width, _ := Size(f)
+	return width
+}
+
+func Size(f *os.File) (width int, height int) {
+	width, height = size(f)
+	if width <= 0 {
+		width = 80
+	}
+	if height <= 0 {
+		height = 24
+	}
+	return width, height
+}
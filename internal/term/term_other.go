@@ -0,0 +1,22 @@
+//go:build !linux
+
+package term
+
+import (
+	"errors"
+	"os"
+)
+
+type State struct{}
+
+func size(f *os.File) (width int, height int) {
+	return 0, 0
+}
+
+func MakeRaw(f *os.File) (*State, error) {
+	return nil, errors.New("raw terminal mode is only supported on Linux")
+}
+
+func Restore(f *os.File, state *State) error {
+	return nil
+}
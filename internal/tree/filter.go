@@ -0,0 +1,149 @@
+package tree
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jobodd/rgst/internal/glob"
+)
+
+// Pattern is an --include or --exclude pattern: a glob, where "**"
+// matches any number of directories, or a regular expression after a
+// "re:" prefix
+type Pattern struct {
+	text  string
+	regex *regexp.Regexp
+}
+
+func ParsePattern(s string) (Pattern, error) {
+	if expr, ok := strings.CutPrefix(s, "re:"); ok {
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			return Pattern{}, fmt.Errorf("Invalid regular expression %q: %s", expr, strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+		}
+		return Pattern{text: s, regex: regex}, nil
+	}
+	if err := glob.Validate(s); err != nil {
+		return Pattern{}, fmt.Errorf("Invalid glob %q: %s", s, err)
+	}
+	return Pattern{text: s}, nil
+}
+
+// Match reports whether path matches. A regular expression may match any
+// part of it; a glob has to match all of it.
+func (p Pattern) Match(path string) bool {
+	path = filepath.ToSlash(path)
+	if p.regex != nil {
+		return p.regex.MatchString(path)
+	}
+	return glob.Match(p.text, path)
+}
+
+func (p Pattern) String() string {
+	return p.text
+}
+
+// Compile checks --regex and compiles it once, before the walk
+func (f *FilterOptions) Compile() error {
+	f.regex = nil
+	if !f.ShouldFilter {
+		return nil
+	}
+	regex, err := regexp.Compile(f.Regex)
+	if err != nil {
+		return fmt.Errorf("Invalid --regex %q: %s", f.Regex, strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+	}
+	f.regex = regex
+	return nil
+}
+
+// Keep says whether a repo passes the filters: --regex against its
+// absolute path, and --include and --exclude against its path from the
+// root unless MatchAbsolute is set
+func (f FilterOptions) Keep(relPath string, absPath string) bool {
+	if f.ShouldFilter && f.regex.MatchString(absPath) == f.ShouldInvertRegExp {
+		return false
+	}
+
+	path := relPath
+	if f.MatchAbsolute {
+		path = absPath
+	}
+	if len(f.Include) > 0 && !matchAny(f.Include, path) {
+		return false
+	}
+	return !matchAny(f.Exclude, path)
+}
+
+func matchAny(patterns []Pattern, path string) bool {
+	for _, p := range patterns {
+		if p.Match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// SavedFilters are the filter and discovery options a scan ran with, kept
+// so a later scan of the same root finds the same repos
+type SavedFilters struct {
+	Regex          string   `json:"regex,omitempty"`
+	InvertRegex    bool     `json:"invertRegex,omitempty"`
+	Include        []string `json:"include,omitempty"`
+	Exclude        []string `json:"exclude,omitempty"`
+	MatchAbsolute  bool     `json:"matchAbsolute,omitempty"`
+	Gitignore      bool     `json:"gitignore,omitempty"`
+	FollowSymlinks bool     `json:"followSymlinks,omitempty"`
+}
+
+func SaveFilters(f FilterOptions, d DiscoveryOptions) SavedFilters {
+	saved := SavedFilters{
+		Regex:          f.Regex,
+		InvertRegex:    f.ShouldInvertRegExp,
+		MatchAbsolute:  f.MatchAbsolute,
+		Gitignore:      d.RespectGitignore,
+		FollowSymlinks: d.FollowSymlinks,
+	}
+	for _, p := range f.Include {
+		saved.Include = append(saved.Include, p.String())
+	}
+	for _, p := range f.Exclude {
+		saved.Exclude = append(saved.Exclude, p.String())
+	}
+	return saved
+}
+
+// Restore compiles the saved options again. QuietErrors isn't saved, as
+// it only changes what's shown.
+func (s SavedFilters) Restore() (FilterOptions, DiscoveryOptions, error) {
+	f := FilterOptions{
+		ShouldFilter:       s.Regex != "",
+		Regex:              s.Regex,
+		ShouldInvertRegExp: s.InvertRegex,
+		MatchAbsolute:      s.MatchAbsolute,
+	}
+	d := DiscoveryOptions{
+		RespectGitignore: s.Gitignore,
+		FollowSymlinks:   s.FollowSymlinks,
+	}
+	if err := f.Compile(); err != nil {
+		return f, d, err
+	}
+	for _, spec := range s.Include {
+		p, err := ParsePattern(spec)
+		if err != nil {
+			return f, d, err
+		}
+		f.Include = append(f.Include, p)
+	}
+	for _, spec := range s.Exclude {
+		p, err := ParsePattern(spec)
+		if err != nil {
+			return f, d, err
+		}
+		f.Exclude = append(f.Exclude, p)
+	}
+	return f, d, nil
+}
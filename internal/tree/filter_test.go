@@ -0,0 +1,60 @@
+package tree
+
+import "testing"
+
+func TestParsePattern(t *testing.T) {
+	cases := map[string]map[string]bool{
+		"work/**":         {"work": true, "work/api": true, "work/libs/db": true, "play/work": false},
+		"**/vendor/*":     {"vendor/lib": true, "api/vendor/lib": true, "api/vendor": false},
+		"*-service":       {"auth-service": true, "work/auth-service": false},
+		"re:-service$":    {"auth-service": true, "work/auth-service": true, "auth-service/web": false},
+		"re:^(a|b),c/\\d": {"a,c/1": true, "c,c/1": false},
+	}
+	for spec, paths := range cases {
+		p, err := ParsePattern(spec)
+		if err != nil {
+			t.Fatalf("Failed test with error: %s", err)
+		}
+		for path, want := range paths {
+			if got := p.Match(path); got != want {
+				t.Fatalf(`Failed test: %s %s Got: %v, Want: %v`, spec, path, got, want)
+			}
+		}
+	}
+
+	for _, spec := range []string{"work/[", "re:(api"} {
+		if _, err := ParsePattern(spec); err == nil {
+			t.Fatalf(`Failed test: %s Got: nil, Want: an error`, spec)
+		}
+	}
+}
+
+func TestFilterOptions_Keep(t *testing.T) {
+	include, _ := ParsePattern("work/**")
+	exclude, _ := ParsePattern("re:legacy")
+	f := FilterOptions{Include: []Pattern{include}, Exclude: []Pattern{exclude}, Regex: "^/home", ShouldFilter: true}
+	if err := f.Compile(); err != nil {
+		t.Fatalf("Failed test with error: %s", err)
+	}
+
+	cases := []struct {
+		relPath string
+		absPath string
+		want    bool
+	}{
+		{"work/api", "/home/me/work/api", true},
+		{"work/legacy-api", "/home/me/work/legacy-api", false},
+		{"play/game", "/home/me/play/game", false},
+		{"work/api", "/srv/work/api", false},
+	}
+	for _, c := range cases {
+		if got := f.Keep(c.relPath, c.absPath); got != c.want {
+			t.Fatalf(`Failed test: %s Got: %v, Want: %v`, c.relPath, got, c.want)
+		}
+	}
+
+	f.Regex = "("
+	if err := f.Compile(); err == nil {
+		t.Fatalf(`Failed test: Got: nil, Want: an error for a bad --regex`)
+	}
+}
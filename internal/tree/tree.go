@@ -1,31 +1,50 @@
 package tree
 
 import (
+	"errors"
 	"io/fs"
-	"log"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/ignore"
 )
 
 type Node struct {
-	FolderName      string
-	AbsPath         string
-	Parent          *Node
-	Children        []*Node
-	IsGitRepo       bool
-	GitStats        git.GitStats
-	FolderTreeWidth int
-	BranchNameWidth int
-	GitStatsWidth   int
+	FolderName      string           `json:"folderName"`
+	AbsPath         string           `json:"absPath"`
+	RealPath        string           `json:"realPath,omitempty"`
+	Parent          *Node            `json:"-"`
+	Children        []*Node          `json:"children,omitempty"`
+	IsGitRepo       bool             `json:"isGitRepo"`
+	GitStats        git.GitStats     `json:"gitStats"`
+	UpdateResult    git.UpdateResult `json:"updateResult"`
+	Violations      []Violation      `json:"violations,omitempty"`
+	Error           string           `json:"error,omitempty"`
+	FolderTreeWidth int              `json:"-"`
+	BranchNameWidth int              `json:"-"`
+	GitStatsWidth   int              `json:"-"`
+}
+
+type Violation struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
 }
 
 type FilterOptions struct {
 	ShouldFilter       bool
 	Regex              string
 	ShouldInvertRegExp bool
+	Include            []Pattern
+	Exclude            []Pattern
+	MatchAbsolute      bool
+	// QuietErrors hides directories that couldn't be read
+	QuietErrors bool
+	regex       *regexp.Regexp
 }
 
 func NewNode(folderName, absPath string, parent *Node) *Node {
@@ -43,6 +62,27 @@ func (n *Node) GetDepth() int {
 	return depth
 }
 
+// RelPath is the path from the root node, e.g. "dbms/postgres"
+func (n *Node) RelPath() string {
+	if n.Parent == nil {
+		return "."
+	}
+	if n.Parent.Parent == nil {
+		return n.FolderName
+	}
+	return filepath.Join(n.Parent.RelPath(), n.FolderName)
+}
+
+func CountGitRepos(root *Node) int {
+	count := 0
+	Walk(root, func(n *Node) {
+		if n.IsGitRepo {
+			count++
+		}
+	})
+	return count
+}
+
 func GetDepth(n *Node, depthPtr *int) {
 	if n.Parent == nil {
 		return
@@ -63,6 +103,14 @@ func Walk(node *Node, visit func(*Node)) {
 	}
 }
 
+// LinkParents restores the Parent pointers of a tree decoded from JSON
+func LinkParents(node *Node) {
+	for _, child := range node.Children {
+		child.Parent = node
+		LinkParents(child)
+	}
+}
+
 func FilterNodes(node *Node, filterOpts FilterOptions) *Node {
 	if node == nil {
 		return nil
@@ -81,24 +129,7 @@ func FilterNodes(node *Node, filterOpts FilterOptions) *Node {
 	node.Children = filteredChildren
 
 	// Check if this node matches
-	keepNode := false
-	if node.IsGitRepo {
-		keepNode = true
-
-		if filterOpts.ShouldFilter {
-			m, err := regexp.MatchString(filterOpts.Regex, node.AbsPath)
-			if err != nil {
-				log.Fatal(err)
-			}
-			if !m {
-				keepNode = false
-			}
-
-			if filterOpts.ShouldInvertRegExp {
-				keepNode = !keepNode
-			}
-		}
-	}
+	keepNode := (node.IsGitRepo || node.Error != "" && !filterOpts.QuietErrors) && filterOpts.Keep(node.RelPath(), node.AbsPath)
 
 	// Keep the node if it matches, or has any matching children
 	if keepNode || len(filteredChildren) > 0 {
@@ -117,11 +148,61 @@ func isGitDirectory(basePath string, directory fs.DirEntry) (bool, error) {
 	return info.IsDir(), nil
 }
 
-func GetGitDirectories(node *Node, depth uint, recurseDepth uint, maxDirLength *int) {
-	if depth > recurseDepth {
-		return
+// DiscoveryOptions change which directories are searched for repos
+type DiscoveryOptions struct {
+	// RespectGitignore skips directories ignored by the repo they're in,
+	// e.g. dependencies checked out under a build folder
+	RespectGitignore bool
+	// FollowSymlinks walks into symlinked directories. Each directory is
+	// only walked once, however many links lead to it.
+	FollowSymlinks bool
+}
+
+// ignores are the rules in effect in a directory. .rgstignore files apply
+// from the root down; a repo's .gitignore files only apply within it,
+// up to the next nested repo.
+type ignores struct {
+	rgst      *ignore.Matcher
+	git       *ignore.Matcher
+	gitignore bool
+}
+
+// enter returns the rules in effect inside n
+func (ig ignores) enter(n *Node) ignores {
+	ig.rgst = ig.rgst.AddFile(n.AbsPath, filepath.Join(n.AbsPath, ignore.File))
+	if !ig.gitignore {
+		return ig
 	}
+	if n.IsGitRepo {
+		ig.git = (*ignore.Matcher)(nil).AddFile(n.AbsPath, filepath.Join(git.GitDir(n.AbsPath), "info", "exclude"))
+	}
+	ig.git = ig.git.AddFile(n.AbsPath, filepath.Join(n.AbsPath, ".gitignore"))
+	return ig
+}
 
+func (ig ignores) ignored(absPath string) bool {
+	return ig.rgst.Ignored(absPath) || ig.git.Ignored(absPath)
+}
+
+type discovery struct {
+	recurseDepth uint
+	opts         DiscoveryOptions
+	// real paths of the directories walked, when following symlinks
+	seen map[string]bool
+	// directories waiting to be walked, once following symlinks
+	queue     []queued
+	following bool
+}
+
+type queued struct {
+	parent *Node
+	name   string
+	depth  uint
+	ig     ignores
+	isLink bool
+}
+
+func GetGitDirectories(node *Node, depth uint, recurseDepth uint, maxDirLength *int, discoveryOpts DiscoveryOptions) {
 	// check for the initial node
 	if node.Parent == nil {
 		dirPath := filepath.Join(node.AbsPath)
@@ -131,24 +212,119 @@ func GetGitDirectories(node *Node, depth uint, recurseDepth uint, maxDirLength *
 		}
 	}
 
+	d := &discovery{recurseDepth: recurseDepth, opts: discoveryOpts, seen: map[string]bool{}}
+	if discoveryOpts.FollowSymlinks {
+		if realPath, err := filepath.EvalSymlinks(node.AbsPath); err == nil {
+			d.seen[realPath] = true
+			if realPath != node.AbsPath {
+				node.RealPath = realPath
+			}
+		}
+	}
+	d.walk(node, depth, ignores{gitignore: discoveryOpts.RespectGitignore})
+
+	// links are only followed once everything reachable without them has
+	// been walked, so repos are shown at their real path where they can be.
+	// After that the walk is breadth first, so the shortest path to a
+	// directory wins.
+	d.following = true
+	for len(d.queue) > 0 {
+		q := d.queue[0]
+		d.queue = d.queue[1:]
+		d.add(q.parent, q.name, q.depth, q.ig, q.isLink)
+	}
+}
+
+func (d *discovery) walk(node *Node, depth uint, ig ignores) {
+	if depth > d.recurseDepth {
+		return
+	}
+
+	// an unreadable directory, e.g. another user's, is recorded and the
+	// walk carries on with whatever could be read
 	entries, err := os.ReadDir(node.AbsPath)
 	if err != nil {
-		panic(err)
+		node.Error = readError(err)
 	}
 
+	ig = ig.enter(node)
 	for _, entry := range entries {
-		if entry.IsDir() {
-			dirPath := filepath.Join(node.AbsPath, entry.Name())
-			gitPath := filepath.Join(dirPath, ".git")
-
-			childNodePtr := NewNode(entry.Name(), dirPath, node)
-			node.Children = append(node.Children, childNodePtr)
-			if _, err := os.Stat(gitPath); err == nil {
-				childNodePtr.IsGitRepo = true
-			}
+		isLink := entry.Type()&fs.ModeSymlink != 0
+		switch {
+		case entry.IsDir() && !d.following:
+			d.add(node, entry.Name(), depth, ig, false)
+		case entry.IsDir() || isLink && d.opts.FollowSymlinks:
+			d.queue = append(d.queue, queued{parent: node, name: entry.Name(), depth: depth, ig: ig, isLink: isLink})
+		}
+	}
+
+}
 
-			GetGitDirectories(childNodePtr, depth+1, recurseDepth, maxDirLength)
+func (d *discovery) add(parent *Node, name string, depth uint, ig ignores, isLink bool) {
+	dirPath := filepath.Join(parent.AbsPath, name)
+	if ig.ignored(dirPath) {
+		return
+	}
+
+	childNodePtr := NewNode(name, dirPath, parent)
+	if d.opts.FollowSymlinks {
+		realPath := filepath.Join(parent.realPath(), name)
+		if isLink {
+			var ok bool
+			if realPath, ok = resolveDir(dirPath); !ok {
+				return
+			}
+		}
+		// a symlink loop, or a directory reachable through several paths
+		if d.seen[realPath] {
+			return
+		}
+		d.seen[realPath] = true
+		if realPath != dirPath {
+			childNodePtr.RealPath = realPath
 		}
 	}
 
+	parent.Children = append(parent.Children, childNodePtr)
+	if d.following {
+		// keep the children in the order ReadDir listed them
+		slices.SortStableFunc(parent.Children, func(a *Node, b *Node) int {
+			return strings.Compare(a.FolderName, b.FolderName)
+		})
+	}
+
+	gitPath := filepath.Join(dirPath, ".git")
+	if _, err := os.Stat(gitPath); err == nil {
+		childNodePtr.IsGitRepo = true
+	}
+
+	d.walk(childNodePtr, depth+1, ig)
+}
+
+func readError(err error) string {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return "can't read directory: " + err.Error()
+}
+
+func (n *Node) realPath() string {
+	if n.RealPath != "" {
+		return n.RealPath
+	}
+	return n.AbsPath
+}
+
+// resolveDir returns the real path of a symlink, or false if it's broken
+// or doesn't lead to a directory
+func resolveDir(dirPath string) (string, bool) {
+	realPath, err := filepath.EvalSymlinks(dirPath)
+	if err != nil {
+		return "", false
+	}
+	if info, err := os.Stat(realPath); err != nil || !info.IsDir() {
+		return "", false
+	}
+	return realPath, true
 }
@@ -0,0 +1,91 @@
+package tree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetGitDirectories_FollowSymlinks(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"links", "real/api/.git", "real/web/.git"} {
+		os.MkdirAll(filepath.Join(root, dir), 0700)
+	}
+	os.Symlink(filepath.Join(root, "real", "api"), filepath.Join(root, "links", "api"))
+	os.Symlink(filepath.Join(root, "real", "web"), filepath.Join(root, "links", "site"))
+	os.Symlink(root, filepath.Join(root, "links", "loop"))
+	os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "links", "broken"))
+
+	repos := func(node *Node) map[string]string {
+		found := map[string]string{}
+		Walk(node, func(n *Node) {
+			if n.IsGitRepo {
+				found[n.RelPath()] = n.RealPath
+			}
+		})
+		return found
+	}
+
+	node := NewNode("ws", root, nil)
+	GetGitDirectories(node, 0, 3, new(int), DiscoveryOptions{})
+	if got := repos(node); len(got) != 2 || got["real/api"] != "" || got["real/web"] != "" {
+		t.Fatalf(`Failed test: Got: %v, Want: real/api and real/web`, got)
+	}
+
+	// reachable without the links, so they're shown once at their real path
+	node = NewNode("ws", root, nil)
+	GetGitDirectories(node, 0, 3, new(int), DiscoveryOptions{FollowSymlinks: true})
+	if got := repos(node); len(got) != 2 || got["real/api"] != "" || got["real/web"] != "" {
+		t.Fatalf(`Failed test: Got: %v, Want: real/api and real/web`, got)
+	}
+
+	links := filepath.Join(root, "links")
+	node = NewNode("links", links, nil)
+	GetGitDirectories(node, 0, 3, new(int), DiscoveryOptions{FollowSymlinks: true})
+	got := repos(node)
+	realRoot, _ := filepath.EvalSymlinks(root)
+	if len(got) != 2 || got["api"] != filepath.Join(realRoot, "real", "api") || got["site"] != filepath.Join(realRoot, "real", "web") {
+		t.Fatalf(`Failed test: Got: %v, Want: api and site with their real paths`, got)
+	}
+}
+
+// a file where a directory should be fails to read whoever runs it,
+// root included
+func TestGetGitDirectories_NotADirectory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(file, []byte("not a directory"), 0600)
+
+	node := NewNode("file", file, nil)
+	GetGitDirectories(node, 0, 3, new(int), DiscoveryOptions{})
+	if want := "can't read directory: not a directory"; node.Error != want {
+		t.Fatalf(`Failed test: Got: %v, Want: %v`, node.Error, want)
+	}
+	if got := FilterNodes(node, FilterOptions{}); got == nil || got.Error == "" {
+		t.Fatalf(`Failed test: Got: %+v, Want the node kept with its error`, got)
+	}
+}
+
+func TestGetGitDirectories_Unreadable(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("root can read every directory")
+	}
+	root := t.TempDir()
+	for _, dir := range []string{"mine/.git", "theirs/secret/.git"} {
+		os.MkdirAll(filepath.Join(root, dir), 0700)
+	}
+	os.Chmod(filepath.Join(root, "theirs"), 0)
+	defer os.Chmod(filepath.Join(root, "theirs"), 0700)
+
+	node := NewNode("ws", root, nil)
+	GetGitDirectories(node, 0, 3, new(int), DiscoveryOptions{})
+	if len(node.Children) != 2 || node.Children[1].Error != "can't read directory: permission denied" {
+		t.Fatalf(`Failed test: Got: %+v, Want: mine and theirs with an error`, node.Children)
+	}
+
+	if got := FilterNodes(node, FilterOptions{}); len(got.Children) != 2 {
+		t.Fatalf(`Failed test: Got: %d children, Want: 2, keeping the error`, len(got.Children))
+	}
+	if got := FilterNodes(node, FilterOptions{QuietErrors: true}); len(got.Children) != 1 {
+		t.Fatalf(`Failed test: Got: %d children, Want: 1 with --quiet-errors`, len(got.Children))
+	}
+}
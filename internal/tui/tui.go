@@ -0,0 +1,355 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jobodd/rgst/internal/git"
+	"github.com/jobodd/rgst/internal/term"
+	t "github.com/jobodd/rgst/internal/tree"
+)
+
+const (
+	enterAltScreen = "\033[?1049h\033[?25l"
+	leaveAltScreen = "\033[?25h\033[?1049l"
+	reverseVideo   = "\033[7m"
+	resetStyle     = "\033[0m"
+	keyHelp        = "↑/↓ move  enter expand  f fetch  p pull  r refresh  s shell  e editor  q quit"
+)
+
+type Options struct {
+	GitOptions git.GitOptions
+	// Lines renders one line per node, in the same order as tree.Walk
+	Lines   func(root *t.Node) []string
+	Refresh func(n *t.Node) error
+}
+
+type details struct {
+	files    []string
+	branches []string
+	stashes  []string
+	err      error
+}
+
+type model struct {
+	root     *t.Node
+	opts     Options
+	in       *os.File
+	out      *os.File
+	state    *term.State
+	nodes    []*t.Node
+	cursor   int
+	offset   int
+	expanded map[*t.Node]*details
+	status   string
+}
+
+func Run(root *t.Node, opts Options) error {
+	in, out := os.Stdin, os.Stdout
+	if !term.IsTerminal(in) || !term.IsTerminal(out) {
+		return errors.New("Interactive mode needs a terminal")
+	}
+
+	m := &model{
+		root:     root,
+		opts:     opts,
+		in:       in,
+		out:      out,
+		expanded: map[*t.Node]*details{},
+	}
+	t.Walk(root, func(n *t.Node) {
+		m.nodes = append(m.nodes, n)
+	})
+	m.cursor = m.nextRepo(-1, 1)
+	if m.cursor == -1 {
+		return errors.New("No git repositories found")
+	}
+
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return err
+	}
+	m.state = state
+	fmt.Fprint(out, enterAltScreen)
+	defer func() {
+		fmt.Fprint(out, leaveAltScreen)
+		term.Restore(in, state)
+	}()
+
+	keys := bufio.NewReader(in)
+	for {
+		m.draw()
+		key, err := readKey(keys)
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "q", "\x03":
+			return nil
+		case "up", "k":
+			m.move(-1)
+		case "down", "j":
+			m.move(1)
+		case "enter", " ", "right", "l":
+			m.toggle()
+		case "left", "h":
+			delete(m.expanded, m.selected())
+		case "f":
+			m.update(false)
+		case "p":
+			m.update(true)
+		case "r":
+			m.refresh(m.selected())
+			m.status = "refreshed " + m.selected().RelPath()
+		case "s":
+			m.runInRepo(shell(), exec.Command(shell()))
+		case "e":
+			m.runInRepo(editor(), editorCommand(editor(), "."))
+		}
+	}
+}
+
+func (m *model) selected() *t.Node {
+	return m.nodes[m.cursor]
+}
+
+func (m *model) nextRepo(from int, step int) int {
+	for i := from + step; i >= 0 && i < len(m.nodes); i += step {
+		if m.nodes[i].IsGitRepo {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *model) move(step int) {
+	if next := m.nextRepo(m.cursor, step); next != -1 {
+		m.cursor = next
+	}
+}
+
+func (m *model) toggle() {
+	n := m.selected()
+	if _, ok := m.expanded[n]; ok {
+		delete(m.expanded, n)
+		return
+	}
+	m.expanded[n] = loadDetails(n)
+}
+
+func loadDetails(n *t.Node) *details {
+	d := &details{files: n.GitStats.ChangedFiles}
+	d.branches, d.err = git.ListBranches(n.AbsPath)
+	if d.err == nil {
+		d.stashes, d.err = git.ListStashes(n.AbsPath)
+	}
+	return d
+}
+
+func (m *model) refresh(n *t.Node) {
+	if err := m.opts.Refresh(n); err != nil {
+		m.status = err.Error()
+		return
+	}
+	if _, ok := m.expanded[n]; ok {
+		m.expanded[n] = loadDetails(n)
+	}
+}
+
+func (m *model) update(pull bool) {
+	n := m.selected()
+	gitOpts := git.GitOptions{
+		ShouldFetch:   !pull,
+		ShouldPull:    pull,
+		PullRebase:    m.opts.GitOptions.PullRebase,
+		PullAutostash: m.opts.GitOptions.PullAutostash,
+	}
+	action := "fetch"
+	if pull {
+		action = "pull"
+	}
+
+	m.status = fmt.Sprintf("%s %s...", action, n.RelPath())
+	m.draw()
+	n.UpdateResult = git.UpdateDirectory(n.AbsPath, gitOpts)
+	m.refresh(n)
+	m.status = fmt.Sprintf("%s %s: %s", action, n.RelPath(), n.UpdateResult)
+}
+
+// runInRepo hands the terminal over to another program, then picks up
+// whatever it changed in the repo
+func (m *model) runInRepo(name string, cmd *exec.Cmd) {
+	n := m.selected()
+	cmd.Dir = n.AbsPath
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	fmt.Fprint(m.out, leaveAltScreen)
+	term.Restore(m.in, m.state)
+	err := cmd.Run()
+	term.MakeRaw(m.in)
+	fmt.Fprint(m.out, enterAltScreen)
+
+	if err != nil {
+		m.status = fmt.Sprintf("%s: %s", name, err)
+	} else {
+		m.status = fmt.Sprintf("%s exited", name)
+	}
+	m.refresh(n)
+}
+
+func shell() string {
+	if s := os.Getenv("SHELL"); s != "" {
+		return s
+	}
+	return "/bin/sh"
+}
+
+func editor() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if e := os.Getenv(env); e != "" {
+			return e
+		}
+	}
+	return "vi"
+}
+
+// editorCommand runs the editor through the shell, as git does, so
+// settings like "code --wait" or a quoted path work
+func editorCommand(editor string, path string) *exec.Cmd {
+	return exec.Command("/bin/sh", "-c", editor+` "$@"`, editor, path)
+}
+
+func (m *model) draw() {
+	width, height := term.Size(m.out)
+
+	var rows []string
+	selectedRow := 0
+	lines := m.opts.Lines(m.root)
+	for i, n := range m.nodes {
+		marker := "  "
+		if i == m.cursor {
+			marker = reverseVideo + ">" + resetStyle + " "
+			selectedRow = len(rows)
+		}
+		rows = append(rows, marker+lines[i])
+
+		if d, ok := m.expanded[n]; ok {
+			indent := strings.Repeat("  ", n.GetDepth()+3)
+			rows = append(rows, detailRows(indent, d)...)
+		}
+	}
+
+	// keep the selection on screen, leaving room for the header and footer
+	bodyHeight := max(1, height-3)
+	if selectedRow < m.offset {
+		m.offset = selectedRow
+	}
+	if selectedRow >= m.offset+bodyHeight {
+		m.offset = selectedRow - bodyHeight + 1
+	}
+	end := min(len(rows), m.offset+bodyHeight)
+
+	var sb strings.Builder
+	sb.WriteString("\033[H")
+	writeLine(&sb, "rgst "+m.root.AbsPath, width)
+	for _, row := range rows[m.offset:end] {
+		writeLine(&sb, row, width)
+	}
+	for i := end - m.offset; i < bodyHeight; i++ {
+		writeLine(&sb, "", width)
+	}
+	writeLine(&sb, m.status, width)
+	sb.WriteString(truncate(keyHelp, width))
+	sb.WriteString("\033[K\033[J")
+	fmt.Fprint(m.out, sb.String())
+}
+
+func detailRows(indent string, d *details) []string {
+	if d.err != nil {
+		return []string{indent + d.err.Error()}
+	}
+
+	var rows []string
+	section := func(title string, items []string) {
+		rows = append(rows, fmt.Sprintf("%s%s (%d)", indent, title, len(items)))
+		for _, item := range items {
+			rows = append(rows, indent+"  "+item)
+		}
+	}
+	section("Changed files", d.files)
+	section("Branches", d.branches)
+	section("Stashes", d.stashes)
+	return rows
+}
+
+// raw mode turns off output processing, so lines need an explicit \r
+func writeLine(sb *strings.Builder, line string, width int) {
+	sb.WriteString(truncate(line, width))
+	sb.WriteString(resetStyle + "\033[K\r\n")
+}
+
+// truncate cuts a line to the terminal width without counting or
+// splitting ANSI escape sequences
+func truncate(s string, width int) string {
+	var sb strings.Builder
+	visible := 0
+	inEscape := false
+	for _, r := range s {
+		switch {
+		case inEscape:
+			sb.WriteRune(r)
+			if r >= '@' && r <= '~' && r != '[' {
+				inEscape = false
+			}
+		case r == '\033':
+			inEscape = true
+			sb.WriteRune(r)
+		case visible < width:
+			visible++
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func readKey(r *bufio.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+
+	switch b {
+	case '\r', '\n':
+		return "enter", nil
+	case '\033':
+		// a lone escape has nothing buffered behind it
+		if r.Buffered() == 0 {
+			return "esc", nil
+		}
+		seq := []byte{}
+		for r.Buffered() > 0 {
+			c, _ := r.ReadByte()
+			seq = append(seq, c)
+			if c >= 'A' && c <= 'Z' || c == '~' {
+				break
+			}
+		}
+		switch string(seq) {
+		case "[A", "OA":
+			return "up", nil
+		case "[B", "OB":
+			return "down", nil
+		case "[C", "OC":
+			return "right", nil
+		case "[D", "OD":
+			return "left", nil
+		}
+		return "esc", nil
+	}
+	return string(b), nil
+}
@@ -0,0 +1,38 @@
+package tui
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestTruncate_KeepsEscapes(t *testing.T) {
+	got := truncate("ab\033[32m+1\033[0m cd", 3)
+	want := "ab\033[32m+\033[0m"
+	if got != want {
+		t.Fatalf(`Failed test: Got: %q, Want: %q`, got, want)
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("\033[Aj\r"))
+	for _, want := range []string{"up", "j", "enter"} {
+		got, err := readKey(r)
+		if err != nil {
+			t.Fatalf("Failed test with error: %s", err)
+		}
+		if got != want {
+			t.Fatalf(`Failed test: Got: %v, Want: %v`, got, want)
+		}
+	}
+}
+
+func TestEditorCommand_SplitsArguments(t *testing.T) {
+	out, err := editorCommand("printf '%s|'", "my repo").Output()
+	if err != nil {
+		t.Fatalf("Failed test with error: %s", err)
+	}
+	if got, want := string(out), "my repo|"; got != want {
+		t.Fatalf(`Failed test: Got: %q, Want: %q`, got, want)
+	}
+}
@@ -0,0 +1,130 @@
+package watch
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/jobodd/rgst/internal/git"
+)
+
+const inotifyMask = syscall.IN_CREATE |
+	syscall.IN_DELETE |
+	syscall.IN_CLOSE_WRITE |
+	syscall.IN_MODIFY |
+	syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO
+
+type inotify struct {
+	w     *Watcher
+	fd    int
+	file  *os.File
+	mu    sync.Mutex
+	repos map[int]string
+	dirs  map[int]string
+}
+
+func startInotify(w *Watcher, repos []string) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+
+	in := &inotify{
+		w:     w,
+		fd:    fd,
+		file:  os.NewFile(uintptr(fd), "inotify"),
+		repos: map[int]string{},
+		dirs:  map[int]string{},
+	}
+
+	for _, repo := range repos {
+		if err := in.addRepo(repo); err != nil {
+			// most likely fs.inotify.max_user_watches; polling still works
+			in.file.Close()
+			return err
+		}
+	}
+
+	w.close = func() { in.file.Close() }
+	go in.read()
+	return nil
+}
+
+func (in *inotify) addRepo(repo string) error {
+	var err error
+	walkDirs(repo, func(dir string) {
+		if err == nil {
+			err = in.add(repo, dir)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	gitDir := git.GitDir(repo)
+	if err := in.add(repo, gitDir); err != nil {
+		return err
+	}
+	return filepath.WalkDir(filepath.Join(gitDir, "refs"), func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		return in.add(repo, path)
+	})
+}
+
+func (in *inotify) add(repo string, dir string) error {
+	wd, err := syscall.InotifyAddWatch(in.fd, dir, inotifyMask)
+	if err != nil {
+		return err
+	}
+	in.mu.Lock()
+	in.repos[wd] = repo
+	in.dirs[wd] = dir
+	in.mu.Unlock()
+	return nil
+}
+
+func (in *inotify) read() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := in.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			name := string(bytes.TrimRight(nameBytes, "\x00"))
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			in.mu.Lock()
+			repo, ok := in.repos[int(event.Wd)]
+			dir := in.dirs[int(event.Wd)]
+			in.mu.Unlock()
+			if !ok || isNoise(name) {
+				continue
+			}
+
+			// new directories need watches of their own
+			if event.Mask&syscall.IN_ISDIR != 0 && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				in.addNewDir(repo, filepath.Join(dir, name))
+			}
+			in.w.changed(repo)
+		}
+	}
+}
+
+func (in *inotify) addNewDir(repo string, dir string) {
+	if filepath.Base(dir) == ".git" {
+		return
+	}
+	walkDirs(dir, func(d string) {
+		in.add(repo, d)
+	})
+}
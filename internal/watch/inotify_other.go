@@ -0,0 +1,9 @@
+//go:build !linux
+
+package watch
+
+import "errors"
+
+func startInotify(w *Watcher, repos []string) error {
+	return errors.New("inotify is only available on Linux")
+}
@@ -0,0 +1,92 @@
+package watch
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jobodd/rgst/internal/git"
+)
+
+func startPolling(w *Watcher, repos []string, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	fingerprints := map[string]uint64{}
+	for _, repo := range repos {
+		fingerprints[repo] = fingerprint(repo)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				for _, repo := range repos {
+					f := fingerprint(repo)
+					if f != fingerprints[repo] {
+						fingerprints[repo] = f
+						w.changed(repo)
+					}
+				}
+			case <-w.done:
+				return
+			}
+		}
+	}()
+}
+
+// fingerprint hashes the name, size and mtime of everything that can
+// change what git reports: the working tree plus HEAD, the index and refs.
+func fingerprint(repo string) uint64 {
+	h := fnv.New64a()
+	record := func(path string, info fs.FileInfo) {
+		fmt.Fprintf(h, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+
+	fmt.Fprintf(h, "%d\n", workTreeFingerprint(repo))
+
+	gitDir := git.GitDir(repo)
+	for _, name := range []string{"HEAD", "index", "packed-refs", "FETCH_HEAD"} {
+		if info, err := os.Stat(filepath.Join(gitDir, name)); err == nil {
+			record(name, info)
+		}
+	}
+	filepath.WalkDir(filepath.Join(gitDir, "refs"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || isNoise(d.Name()) {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			record(path, info)
+		}
+		return nil
+	})
+
+	return h.Sum64()
+}
+
+// workTreeFingerprint hashes the name, size and mtime of every file in
+// the working tree, so edits that haven't touched the index still show up.
+func workTreeFingerprint(repo string) uint64 {
+	h := fnv.New64a()
+	walkDirs(repo, func(dir string) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if entry.Name() == ".git" || isNoise(entry.Name()) {
+				continue
+			}
+			if info, err := entry.Info(); err == nil {
+				fmt.Fprintf(h, "%s %d %d\n", filepath.Join(dir, entry.Name()), info.Size(), info.ModTime().UnixNano())
+			}
+		}
+	})
+	return h.Sum64()
+}
@@ -0,0 +1,108 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPollInterval = 2 * time.Second
+	settleDelay         = 250 * time.Millisecond
+)
+
+// Watcher reports which repositories changed. Changes are batched until
+// the filesystem has been quiet for a moment, so a checkout touching
+// thousands of files is reported once.
+type Watcher struct {
+	Events  chan []string
+	Backend string
+
+	mu      sync.Mutex
+	pending map[string]bool
+	timer   *time.Timer
+	done    chan struct{}
+	close   func()
+}
+
+// New watches the working tree and git directory of each repo, using
+// inotify where available and falling back to polling.
+func New(repos []string, pollInterval time.Duration) *Watcher {
+	w := &Watcher{
+		Events:  make(chan []string, 1),
+		pending: map[string]bool{},
+		done:    make(chan struct{}),
+	}
+
+	if err := startInotify(w, repos); err == nil {
+		w.Backend = "inotify"
+		return w
+	}
+
+	w.Backend = "polling"
+	startPolling(w, repos, pollInterval)
+	return w
+}
+
+func (w *Watcher) Close() {
+	close(w.done)
+	if w.close != nil {
+		w.close()
+	}
+}
+
+func (w *Watcher) changed(repo string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending[repo] = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(settleDelay, w.flush)
+}
+
+func (w *Watcher) flush() {
+	w.mu.Lock()
+	var repos []string
+	for repo := range w.pending {
+		repos = append(repos, repo)
+	}
+	w.pending = map[string]bool{}
+	w.mu.Unlock()
+
+	if len(repos) == 0 {
+		return
+	}
+	select {
+	case w.Events <- repos:
+	case <-w.done:
+	}
+}
+
+// lock files come and go around every git command, including our own
+func isNoise(name string) bool {
+	return strings.HasSuffix(name, ".lock")
+}
+
+// walkDirs visits the directories of a working tree, skipping .git and
+// any nested repos, which are watched in their own right.
+func walkDirs(repo string, visit func(dir string)) {
+	filepath.WalkDir(repo, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if path != repo {
+			if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		visit(path)
+		return nil
+	})
+}
@@ -0,0 +1,64 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func setupRepo(t *testing.T) string {
+	repo := t.TempDir()
+	os.Mkdir(filepath.Join(repo, ".git"), 0700)
+	os.Mkdir(filepath.Join(repo, ".git", "refs"), 0700)
+	os.Mkdir(filepath.Join(repo, "src"), 0700)
+	return repo
+}
+
+func waitForRepo(t *testing.T, w *Watcher, want string) {
+	select {
+	case got := <-w.Events:
+		if len(got) != 1 || got[0] != want {
+			t.Fatalf(`Failed test: Got: %v, Want: [%v]`, got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Failed test: no change reported for %s", want)
+	}
+}
+
+func TestWatch_Inotify(t *testing.T) {
+	repo := setupRepo(t)
+	w := New([]string{repo}, 0)
+	defer w.Close()
+	if w.Backend != "inotify" {
+		t.Skipf("inotify unavailable, using %s", w.Backend)
+	}
+
+	os.WriteFile(filepath.Join(repo, "src", "main.go"), []byte("package main"), 0600)
+	waitForRepo(t, w, repo)
+}
+
+func TestWatch_Polling(t *testing.T) {
+	repo := setupRepo(t)
+	w := &Watcher{
+		Events:  make(chan []string, 1),
+		Backend: "polling",
+		pending: map[string]bool{},
+		done:    make(chan struct{}),
+	}
+	startPolling(w, []string{repo}, 20*time.Millisecond)
+	defer w.Close()
+
+	os.WriteFile(filepath.Join(repo, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0600)
+	waitForRepo(t, w, repo)
+}
+
+func TestWatch_IgnoresLockFiles(t *testing.T) {
+	repo := setupRepo(t)
+	before := fingerprint(repo)
+	os.WriteFile(filepath.Join(repo, ".git", "index.lock"), nil, 0600)
+	after := fingerprint(repo)
+	if before != after {
+		t.Fatalf("Failed test: lock file changed the fingerprint")
+	}
+}